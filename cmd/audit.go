@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"ai-edr/internal/audit"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// auditCmd 是 "audit" 子命令的分组入口
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "审计日志相关工具",
+}
+
+// auditVerifyCmd 走一遍哈希链，报告第一处被篡改/断裂的记录
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "校验 ~/.deepsentry/audit 下的哈希链审计日志是否被篡改",
+	RunE: func(c *cobra.Command, args []string) error {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(home, ".deepsentry", "audit")
+		passphrase := os.Getenv("DEEPSENTRY_AUDIT_KEY")
+
+		result, err := audit.Verify(dir, passphrase)
+		if err != nil {
+			return fmt.Errorf("校验审计日志失败: %v", err)
+		}
+
+		if result.OK {
+			fmt.Printf("✅ 审计日志完整，共 %d 条记录，哈希链未被破坏\n", result.TotalCount)
+			return nil
+		}
+
+		fmt.Printf("❌ 审计日志存在断裂: %s 第 %d 条记录\n原因: %s\n(共校验 %d 条记录)\n",
+			result.BrokenFile, result.BrokenIndex, result.Reason, result.TotalCount)
+		return fmt.Errorf("哈希链校验失败")
+	},
+}
+
+func init() {
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"ai-edr/internal/baseline"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	baselineProfile string
+	baselineChecks  string
+	baselineJSONOut string
+	baselineXLSXOut string
+)
+
+// baselineCmd 是 "baseline" 子命令的分组入口
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "主机基线合规扫描",
+}
+
+// baselineRunCmd 按 checks.yaml 声明的检查项跑一遍基线扫描；单主机/SSH/Fanout 模式均由 baseline.RunAll 自动识别
+var baselineRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "执行基线合规扫描",
+	RunE: func(c *cobra.Command, args []string) error {
+		path, err := baseline.ResolveChecksPath(baselineChecks, baselineProfile)
+		if err != nil {
+			return err
+		}
+		checks, err := baseline.LoadChecks(path)
+		if err != nil {
+			return err
+		}
+
+		reports, err := baseline.RunAll(checks)
+		if err != nil {
+			return fmt.Errorf("基线扫描失败: %v", err)
+		}
+
+		for _, r := range reports {
+			if r.Err != "" {
+				fmt.Printf("❌ %s: 扫描失败 (%s)\n", r.Host, r.Err)
+				continue
+			}
+			status := "✅"
+			if r.Score < r.MaxScore {
+				status = "⚠️"
+			}
+			fmt.Printf("%s %s: %d/%d\n", status, r.Host, r.Score, r.MaxScore)
+		}
+
+		if baselineJSONOut != "" {
+			if err := baseline.WriteJSON(reports, baselineJSONOut); err != nil {
+				return err
+			}
+			fmt.Printf("📄 JSON 报告已写入: %s\n", baselineJSONOut)
+		}
+		if baselineXLSXOut != "" {
+			if err := baseline.WriteXLSX(reports, baselineXLSXOut); err != nil {
+				return err
+			}
+			fmt.Printf("📊 xlsx 报告已写入: %s\n", baselineXLSXOut)
+		}
+		return nil
+	},
+}
+
+func init() {
+	baselineRunCmd.Flags().StringVar(&baselineProfile, "profile", "", "基线检查项 profile，如 cis-linux-l1")
+	baselineRunCmd.Flags().StringVar(&baselineChecks, "checks", "", "显式指定 checks.yaml 路径，优先级高于 --profile")
+	baselineRunCmd.Flags().StringVar(&baselineJSONOut, "json", "", "将报告写出为 JSON 文件")
+	baselineRunCmd.Flags().StringVar(&baselineXLSXOut, "xlsx", "", "将报告写出为 xlsx 文件")
+
+	baselineCmd.AddCommand(baselineRunCmd)
+	rootCmd.AddCommand(baselineCmd)
+}
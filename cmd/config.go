@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"ai-edr/internal/config"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configCmd 是 "config" 子命令的分组入口
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "配置文件相关工具",
+}
+
+// configSealCmd 把当前 config.yaml 中明文的敏感字段原地加密
+var configSealCmd = &cobra.Command{
+	Use:   "seal",
+	Short: "将 config.yaml 中的明文 api_key/ssh_password 转换为加密存储",
+	RunE: func(c *cobra.Command, args []string) error {
+		return transformSensitiveFields(config.SealField, true)
+	},
+}
+
+// configUnsealCmd 把当前 config.yaml 中加密的敏感字段还原为明文（便于迁移/调试）
+var configUnsealCmd = &cobra.Command{
+	Use:   "unseal",
+	Short: "将 config.yaml 中加密的 api_key/ssh_password 还原为明文存储",
+	RunE: func(c *cobra.Command, args []string) error {
+		return transformSensitiveFields(config.UnsealField, false)
+	},
+}
+
+// transformSensitiveFields 对 SensitiveFields 里的每个扁平字段、SensitiveListFields 里每个
+// 列表字段的子字段应用 transform，再写回 config.yaml。和 SaveConfig 处理同一组字段，
+// 只是那边是自动在保存时做加密，这里是 `config seal`/`unseal` 的显式一次性转换。
+// sealing 为 true 时会跳过已经是密文的字段，避免重复加密；UnsealField 本身对明文是
+// 原样返回的幂等操作，所以 unseal 方向不需要这层判断
+func transformSensitiveFields(transform func(string) (string, error), sealing bool) error {
+	for _, field := range config.SensitiveFields {
+		value := viper.GetString(field)
+		if value == "" || (sealing && config.IsSealed(value)) {
+			continue
+		}
+		newValue, err := transform(value)
+		if err != nil {
+			return fmt.Errorf("处理字段 %s 失败: %v", field, err)
+		}
+		viper.Set(field, newValue)
+	}
+
+	for _, lf := range config.SensitiveListFields {
+		raw := viper.Get(lf.Key)
+		items, ok := raw.([]interface{})
+		if !ok || len(items) == 0 {
+			continue
+		}
+
+		transformedItems := make([]interface{}, len(items))
+		for i, it := range items {
+			m, ok := it.(map[string]interface{})
+			if !ok {
+				transformedItems[i] = it
+				continue
+			}
+			transformedCopy := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				transformedCopy[k] = v
+			}
+			if v, ok := m[lf.SubField].(string); ok && v != "" && !(sealing && config.IsSealed(v)) {
+				newValue, err := transform(v)
+				if err != nil {
+					return fmt.Errorf("处理字段 %s[%d].%s 失败: %v", lf.Key, i, lf.SubField, err)
+				}
+				transformedCopy[lf.SubField] = newValue
+			}
+			transformedItems[i] = transformedCopy
+		}
+		viper.Set(lf.Key, transformedItems)
+	}
+
+	if err := viper.WriteConfigAs("config.yaml"); err != nil {
+		return fmt.Errorf("写回 config.yaml 失败: %v", err)
+	}
+	fmt.Println("✅ config.yaml 已更新")
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configSealCmd)
+	configCmd.AddCommand(configUnsealCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"ai-edr/internal/config"
+	"ai-edr/internal/daemon"
+	"ai-edr/internal/security"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonAddr string
+var daemonRecordPath string
+
+// daemonCmd 是 "daemon" 子命令的分组入口
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "deepsentryd 常驻进程相关命令",
+}
+
+// daemonRunCmd 启动 deepsentryd：把 Agent 循环常驻在这个进程里，
+// deepsentry 本体则作为瘦客户端通过 HTTP API 转发用户输入与审批决定
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "启动 deepsentryd，监听 Agent API",
+	RunE: func(c *cobra.Command, args []string) error {
+		// policy.yaml 是 CheckRisk/SafeExecV3 实际生效策略的唯一来源；这里不加载的话
+		// daemon 会一直跑内置默认策略，policy.yaml 里的站点覆盖形同虚设
+		if err := security.LoadPolicy(); err != nil {
+			return fmt.Errorf("加载 policy.yaml 失败: %v", err)
+		}
+		security.WatchPolicyReload()
+
+		d := daemon.NewDaemonCli(config.GlobalConfig)
+		if daemonRecordPath != "" {
+			d.SetRecordPath(daemonRecordPath)
+			fmt.Printf("🎬 已启用录制，Agent 交互将追加写入 %s\n", daemonRecordPath)
+		}
+		fmt.Printf("🔌 deepsentryd 正在监听 %s\n", daemonAddr)
+		fmt.Printf("🔑 API 访问需要携带 token（见 %s 或设置环境变量 DEEPSENTRY_DAEMON_TOKEN）\n", daemon.TokenPathHint())
+		if err := d.Serve(daemonAddr); err != nil {
+			return fmt.Errorf("deepsentryd 退出: %v", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	daemonRunCmd.Flags().StringVar(&daemonAddr, "addr", daemon.DefaultSocketAddr(), "监听地址，支持 unix:///path 或 host:port")
+	daemonRunCmd.Flags().StringVar(&daemonRecordPath, "record", "", "把每一步 Agent 交互录制到这个 JSON Lines 文件，留空则不录制")
+
+	daemonCmd.AddCommand(daemonRunCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
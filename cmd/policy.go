@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"ai-edr/internal/security"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// policyCmd 是 "policy" 子命令的分组入口
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "命令风险策略相关工具",
+}
+
+// policyTestCmd 打印指定命令最终命中的规则与判定原因，便于调试 policy.yaml
+var policyTestCmd = &cobra.Command{
+	Use:   "test \"<cmd>\"",
+	Short: "测试一条命令会被策略引擎判定为何种风险等级",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(c *cobra.Command, args []string) error {
+		if err := security.LoadPolicy(); err != nil {
+			return fmt.Errorf("加载 policy.yaml 失败: %v", err)
+		}
+
+		risk, reason := security.CheckRisk(args[0])
+		fmt.Printf("命令: %s\n风险等级: %s\n判定依据: %s\n", args[0], risk, reason)
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyTestCmd)
+	rootCmd.AddCommand(policyCmd)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"ai-edr/internal/analyzer/provider"
+	"ai-edr/internal/config"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// providersCmd 是 "providers" 子命令的分组入口
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "多 Provider LLM 后端相关工具",
+}
+
+// providersTestCmd 逐个 ping config.yaml 里配置的 provider，报告是否可达
+var providersTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "依次探活 config.yaml 里 providers 列表中的每个后端",
+	RunE: func(c *cobra.Command, args []string) error {
+		specs := config.GlobalConfig.Providers
+		if len(specs) == 0 {
+			specs = []config.ProviderSpec{{
+				Name:        "openai",
+				Role:        "primary",
+				ApiURL:      config.GlobalConfig.ApiURL,
+				ApiKey:      config.GlobalConfig.ApiKey,
+				ModelName:   config.GlobalConfig.ModelName,
+				Temperature: config.GlobalConfig.Temperature,
+			}}
+		}
+
+		failed := 0
+		for _, spec := range specs {
+			p, err := provider.New(provider.Spec{
+				Name:        spec.Name,
+				Role:        spec.Role,
+				ApiURL:      spec.ApiURL,
+				ApiKey:      spec.ApiKey,
+				ModelName:   spec.ModelName,
+				Temperature: spec.Temperature,
+			})
+			if err != nil {
+				failed++
+				fmt.Printf("❌ %s (%s): %v\n", spec.Name, spec.Role, err)
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err = provider.Ping(ctx, p)
+			cancel()
+
+			if err != nil {
+				failed++
+				fmt.Printf("❌ %s: %v\n", p.Name(), err)
+				continue
+			}
+			fmt.Printf("✅ %s 可达\n", p.Name())
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d 个 provider 探活失败", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	providersCmd.AddCommand(providersTestCmd)
+	rootCmd.AddCommand(providersCmd)
+}
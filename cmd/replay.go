@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"ai-edr/internal/analyzer"
+	"ai-edr/internal/collector"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// replayCmd 按录制文件里的顺序把 Agent 循环重新走一遍：不消耗 token、不依赖网络、
+// 不真的执行命令，命令的"输出"直接取自录制文件，用来对真实遇到过的畸形 JSON 做回归测试
+var replayCmd = &cobra.Command{
+	Use:   "replay <recording.jsonl>",
+	Short: "离线回放一份 HAR 风格的 Agent 交互录制文件",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(c *cobra.Command, args []string) error {
+		client, err := analyzer.NewReplayClient(args[0])
+		if err != nil {
+			return fmt.Errorf("加载录制文件失败: %v", err)
+		}
+		restore := analyzer.SetLLMClient(client)
+		defer restore()
+
+		var history []analyzer.Message
+		steps := client.Steps()
+
+		for i := 0; i < client.Len(); i++ {
+			resp, err := analyzer.RunAgentStep(collector.SystemContext{}, &history)
+			if err != nil {
+				return fmt.Errorf("第 %d 步回放失败: %v", i+1, err)
+			}
+
+			fmt.Printf("── 第 %d 步 ──\n思考: %s\n", i+1, resp.Thought)
+			history = append(history, analyzer.Message{Role: "assistant", Content: resp.Thought})
+
+			if resp.Command == "" {
+				if resp.IsFinished {
+					fmt.Printf("最终报告:\n%s\n", resp.FinalReport)
+					return nil
+				}
+				continue
+			}
+
+			stdout := steps[i].Stdout
+			fmt.Printf("命令: %s\n风险等级: %s (%s)\n录制输出:\n%s\n", resp.Command, resp.RiskLevel, resp.Reason, stdout)
+
+			history = append(history, analyzer.Message{Role: "user", Content: fmt.Sprintf("命令: %s\n输出:\n%s", resp.Command, stdout)})
+
+			if resp.IsFinished {
+				fmt.Printf("最终报告:\n%s\n", resp.FinalReport)
+				return nil
+			}
+		}
+
+		fmt.Println("⚠️ 录制文件已回放完毕，但 Agent 尚未报告任务结束")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"ai-edr/internal/config"
+	"ai-edr/internal/executor"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// sshCmd 是 "ssh" 子命令的分组入口，目前只挂载 trust
+var sshCmd = &cobra.Command{
+	Use:   "ssh",
+	Short: "SSH 相关运维命令",
+}
+
+// sshTrustCmd 预先抓取目标主机的公钥并写入托管的 known_hosts，避免首次连接时卡在 strict 模式
+var sshTrustCmd = &cobra.Command{
+	Use:   "trust <host[:port]>",
+	Short: "预先信任一台主机的 SSH 公钥，写入 known_hosts",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(c *cobra.Command, args []string) error {
+		addr := args[0]
+
+		knownHostsPath := config.GlobalConfig.SSHKnownHostsPath
+		if knownHostsPath == "" {
+			return fmt.Errorf("未配置 ssh_known_hosts_path")
+		}
+
+		if err := executor.TrustHost(addr, knownHostsPath); err != nil {
+			return fmt.Errorf("信任主机失败: %v", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	sshCmd.AddCommand(sshTrustCmd)
+	rootCmd.AddCommand(sshCmd)
+}
@@ -1,19 +1,53 @@
 package analyzer
 
 import (
+	"ai-edr/internal/analyzer/provider"
+	"ai-edr/internal/analyzer/recorder"
 	"ai-edr/internal/collector"
 	"ai-edr/internal/config"
 	"ai-edr/internal/security"
-	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
-	"time"
+	"sync"
 )
 
+// routerMu/cachedRouter 懒加载并缓存按当前配置构造的 provider.Router，避免每一步
+// Agent 思考都重新解析一遍 providers 列表
+var (
+	routerMu     sync.Mutex
+	cachedRouter *provider.Router
+)
+
+// activeRecorder 非空时，RunAgentStep 会把每一次 LLM 交互录制下来；
+// 调用方在执行完 resp.Command 之后可以用 activeRecorder.RecordExec 把命令/输出/风险判定补全进同一条记录
+var activeRecorder *recorder.Recorder
+
+// SetRecorder 启用（传 nil 则关闭）对 RunAgentStep 的 HAR 风格录制
+func SetRecorder(r *recorder.Recorder) { activeRecorder = r }
+
+// RecordExec 用命令的实际执行结果补全 activeRecorder 里编号为 index 的那一步（通常取自 LastRecordIndex）；
+// 未启用录制（activeRecorder 为 nil）时直接返回 nil，调用方不必先判空
+func RecordExec(index int, command, stdout, risk, reason string) error {
+	if activeRecorder == nil {
+		return nil
+	}
+	return activeRecorder.RecordExec(index, command, stdout, risk, reason)
+}
+
+// LastRecordIndex 返回 activeRecorder 最近一次 RecordLLM 分配的步骤编号，供调用方在
+// 执行完 resp.Command 之后传给 RecordExec；未启用录制时返回 0（RecordExec 对此是 no-op）
+func LastRecordIndex() int {
+	if activeRecorder == nil {
+		return 0
+	}
+	return activeRecorder.LastIndex()
+}
+
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
@@ -26,14 +60,6 @@ type ChatRequest struct {
 	Temperature float64   `json:"temperature"`
 }
 
-type ChatResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-}
-
 type AgentResponse struct {
 	Thought     string `json:"thought"`
 	Command     string `json:"command"`
@@ -52,17 +78,29 @@ type CompatibilityResponse struct {
 	FinalReport interface{} `json:"final_report"`
 	CmdArray    []string    `json:"cmd"`
 	Explanation string      `json:"explanation"`
-}
 
-// RunAgentStep 执行 Agent 的单步思考
-func RunAgentStep(sysCtx collector.SystemContext, history *[]Message) (AgentResponse, error) {
-	apiKey := config.GlobalConfig.ApiKey
+	// Segments 非空时整条响应走新的分段格式（见 Segment），Command/FinalReport 等扁平字段被忽略，
+	// 避免长脚本、二进制样本这类大块内容挤在同一个扁平字符串字段里引出 extractCommandString 要应对的转义问题
+	Segments []Segment `json:"segments"`
+}
 
-	// 1. 获取基础 System Prompt (来自 collector)
-	basePrompt := sysCtx.GenerateSystemPrompt()
+// Segment 是分段响应里的单个片段，类似 CQ 码的分段消息模型：不同 Type 把各自的数据放进
+// Data 里，由 materializeSegments 按 Type 解释。已知的 Type：
+//   - text：纯文本，拼进 Thought
+//   - command：一条普通命令，Data["command"]
+//   - script：base64 编码的脚本正文，Data["body"]，按 Data["interpreter"]（powershell/cmd/bash）
+//     落地成临时文件后以路径方式调用，而不是把脚本内容塞进 JSON 字符串
+//   - file：base64 编码的附件，目标路径收敛进沙箱目录后生成一条落盘 Command（见
+//     materializeSegments），和 command/script 片段一样要过 CheckRisk/审批，不在解析阶段直接写盘
+//   - markdown：base64 编码的富文本最终报告，Data["body"]，解码后作为 FinalReport
+type Segment struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+}
 
-	// 增强 Windows 路径操作指南 & JSON 约束
-	selfProtectionPrompt := `
+// selfProtectionPrompt 是拼接在 collector 生成的基础 System Prompt 之后的固定约束，
+// RunAgentStep 与 RunAgentStepStream 共用同一份，避免两处各维护一份文案
+const selfProtectionPrompt = `
 【⛔ 核心自我保护守则】
 1. 绝对禁止删除/移动 config.yaml, deepsentry.exe, reports/ 目录。
 
@@ -75,12 +113,28 @@ func RunAgentStep(sysCtx collector.SystemContext, history *[]Message) (AgentResp
 2. **反斜杠 (\) 必须转义为 (\\)**。
    - 错误示例: {"command": "grep "eval" file"}
    - 正确示例: {"command": "grep \"eval\" file"}
+
+【📎 长脚本 / 二进制附件】
+如果要执行的内容是一段较长的脚本或需要落盘的二进制样本，不要把它塞进 "command" 字符串，
+改用 "segments" 数组逐段表达，每段 {"type": "...", "data": {...}}：
+  - {"type": "script", "data": {"interpreter": "powershell|cmd|bash", "body": "<base64>"}}
+  - {"type": "file", "data": {"path": "<落盘路径>", "data": "<base64>"}}
+  - {"type": "markdown", "data": {"body": "<base64>"}}（作为 final_report）
 `
-	systemPrompt := basePrompt + selfProtectionPrompt
+
+// buildSystemPrompt 拼出完整的 System Prompt，RunAgentStep 与 RunAgentStepStream 共用
+func buildSystemPrompt(sysCtx collector.SystemContext) string {
+	return sysCtx.GenerateSystemPrompt() + selfProtectionPrompt
+}
+
+// RunAgentStep 执行 Agent 的单步思考
+func RunAgentStep(sysCtx collector.SystemContext, history *[]Message) (AgentResponse, error) {
+	apiKey := config.GlobalConfig.ApiKey
+	systemPrompt := buildSystemPrompt(sysCtx)
 
 	// Context 滑动窗口：防止 Token 超限
 	if len(*history) > 15 {
-		compressHistory(apiKey, history)
+		compressHistory(history)
 	}
 
 	messages := []Message{
@@ -88,18 +142,33 @@ func RunAgentStep(sysCtx collector.SystemContext, history *[]Message) (AgentResp
 	}
 	messages = append(messages, *history...)
 
-	// 调用 LLM
-	rawResp, err := callLLM(apiKey, messages)
+	// 调用 LLM：走 activeLLMClient 而不是直接调 Router，使得回放场景下
+	// SetLLMClient(NewReplayClient(...)) 能原样接管这一步，不必改调用方
+	rawResp, err := activeLLMClient.Call(apiKey, messages)
 	if err != nil {
 		return AgentResponse{}, err
 	}
 
+	resp := appendUsageReport(buildAgentResponse(rawResp))
+
+	if activeRecorder != nil {
+		if _, err := activeRecorder.RecordLLM(ChatRequest{Model: config.GlobalConfig.ModelName, Messages: messages, Temperature: config.GlobalConfig.Temperature}, resp, rawResp); err != nil {
+			return AgentResponse{}, fmt.Errorf("录制 LLM 交互失败: %v", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// buildAgentResponse 把 LLM 的原始响应文本解析/兜底成结构化的 AgentResponse，
+// 是 RunAgentStep（一次性响应）与 RunAgentStepStream（流式响应拼出的完整文本）共用的收尾逻辑
+func buildAgentResponse(rawResp string) AgentResponse {
 	// 2. 清洗 JSON
 	cleanResp := cleanJSON(rawResp)
 	var compat CompatibilityResponse
 
 	// 3. 尝试标准解析
-	err = json.Unmarshal([]byte(cleanResp), &compat)
+	err := json.Unmarshal([]byte(cleanResp), &compat)
 
 	// 🟢 [核心修复] JSON 解析失败时的智能兜底 (字符级扫描)
 	if err != nil {
@@ -130,7 +199,7 @@ func RunAgentStep(sysCtx collector.SystemContext, history *[]Message) (AgentResp
 					FinalReport: fmt.Sprintf("❌ 解析失败: %v\n原始响应:\n%s", err, rawResp),
 					IsFinished:  true,
 					RiskLevel:   "low",
-				}, nil
+				}
 			}
 		} else {
 			// 补全括号后解析成功
@@ -143,32 +212,51 @@ func RunAgentStep(sysCtx collector.SystemContext, history *[]Message) (AgentResp
 		IsFinished: compat.IsFinished,
 	}
 
-	// 适配 Command (兼容 string 或 []string)
-	if compat.Command != "" {
-		resp.Command = compat.Command
-	} else if len(compat.CmdArray) > 0 {
-		resp.Command = compat.CmdArray[len(compat.CmdArray)-1]
-	}
-
-	// 适配 Thought
-	if compat.Thought != "" {
-		resp.Thought = compat.Thought
-	} else if compat.Explanation != "" {
-		resp.Thought = compat.Explanation
+	if len(compat.Segments) > 0 {
+		segResp, err := materializeSegments(compat.Segments)
+		if err != nil {
+			return AgentResponse{
+				Thought:     "分段响应处理失败",
+				FinalReport: fmt.Sprintf("❌ 处理 segments 失败: %v\n原始响应:\n%s", err, rawResp),
+				IsFinished:  true,
+				RiskLevel:   "low",
+			}
+		}
+		resp.Command = segResp.Command
+		resp.FinalReport = segResp.FinalReport
+		if segResp.Thought != "" {
+			resp.Thought = segResp.Thought
+		} else {
+			resp.Thought = inferThoughtFromCommand(resp.Command)
+		}
 	} else {
-		resp.Thought = inferThoughtFromCommand(resp.Command)
-	}
-
-	// 适配 Report
-	switch v := compat.FinalReport.(type) {
-	case string:
-		resp.FinalReport = v
-	case map[string]interface{}, []interface{}:
-		prettyBytes, _ := json.MarshalIndent(v, "", "  ")
-		resp.FinalReport = string(prettyBytes)
-	default:
-		if v != nil {
-			resp.FinalReport = fmt.Sprintf("%v", v)
+		// 适配 Command (兼容 string 或 []string)
+		if compat.Command != "" {
+			resp.Command = compat.Command
+		} else if len(compat.CmdArray) > 0 {
+			resp.Command = compat.CmdArray[len(compat.CmdArray)-1]
+		}
+
+		// 适配 Thought
+		if compat.Thought != "" {
+			resp.Thought = compat.Thought
+		} else if compat.Explanation != "" {
+			resp.Thought = compat.Explanation
+		} else {
+			resp.Thought = inferThoughtFromCommand(resp.Command)
+		}
+
+		// 适配 Report
+		switch v := compat.FinalReport.(type) {
+		case string:
+			resp.FinalReport = v
+		case map[string]interface{}, []interface{}:
+			prettyBytes, _ := json.MarshalIndent(v, "", "  ")
+			resp.FinalReport = string(prettyBytes)
+		default:
+			if v != nil {
+				resp.FinalReport = fmt.Sprintf("%v", v)
+			}
 		}
 	}
 
@@ -197,11 +285,12 @@ func RunAgentStep(sysCtx collector.SystemContext, history *[]Message) (AgentResp
 		}
 	}
 
-	return resp, nil
+	return resp
 }
 
-// compressHistory 压缩历史记录
-func compressHistory(apiKey string, history *[]Message) error {
+// compressHistory 压缩历史记录：走 Router.ChatSummarize，配了 summarizer（通常是本地
+// Ollama 模型）就用它做摘要，把主推理循环的前沿模型 token 预算都留给真正的分析
+func compressHistory(history *[]Message) error {
 	cutIndex := 10
 	if len(*history) < cutIndex {
 		return nil
@@ -214,7 +303,11 @@ func compressHistory(apiKey string, history *[]Message) error {
 	summaryPrompt = append(summaryPrompt, toSummarize...)
 	summaryPrompt = append(summaryPrompt, Message{Role: "user", Content: "请生成摘要。"})
 
-	summaryText, err := callLLM(apiKey, summaryPrompt)
+	router, err := getRouter()
+	if err != nil {
+		return err
+	}
+	summaryText, err := router.ChatSummarize(context.Background(), toProviderMessages(summaryPrompt))
 	if err != nil {
 		return err
 	}
@@ -226,6 +319,152 @@ func compressHistory(apiKey string, history *[]Message) error {
 	return nil
 }
 
+// materializeSegments 把一组 Segment 落地成一个 AgentResponse：text 片段拼成 Thought，
+// file 片段把目标路径收敛进沙箱目录后生成一条落盘 Command（不在此处直接写盘，交给
+// CheckRisk/审批流程决定是否真的执行），script 片段把脚本正文落地成临时文件后改用路径
+// 调用，command 片段直接作为 Command，markdown 片段解码后作为 FinalReport。
+// 片段按数组顺序处理，后出现的 command/script/file 片段会覆盖前面的 Command
+func materializeSegments(segments []Segment) (AgentResponse, error) {
+	var resp AgentResponse
+	var thoughts []string
+
+	for _, seg := range segments {
+		switch seg.Type {
+		case "text":
+			if t, _ := seg.Data["text"].(string); t != "" {
+				thoughts = append(thoughts, t)
+			}
+
+		case "command":
+			if cmd, _ := seg.Data["command"].(string); cmd != "" {
+				resp.Command = cmd
+			}
+
+		case "file":
+			rawPath, _ := seg.Data["path"].(string)
+			raw, _ := seg.Data["data"].(string)
+			if rawPath == "" || raw == "" {
+				return resp, fmt.Errorf("file 片段缺少 path 或 data")
+			}
+			if _, err := base64.StdEncoding.DecodeString(raw); err != nil {
+				return resp, fmt.Errorf("file 片段 base64 解码失败: %v", err)
+			}
+			safePath, err := sandboxAttachmentPath(rawPath)
+			if err != nil {
+				return resp, fmt.Errorf("file 片段目标路径不合法: %v", err)
+			}
+			// 写入动作不在解析阶段直接发生：path/data 来自 LLM 的 JSON 输出，而 LLM 的输入里
+			// 混有被扫描主机上的远程内容，存在提示注入的可能。这里只生成一条落盘 Command，
+			// 和 command/script 片段一样要先过 CheckRisk、高危时再走人工审批，才会被真正执行
+			resp.Command = fmt.Sprintf(
+				"mkdir -p %s && echo %s | base64 -d > %s",
+				shellQuote(filepath.Dir(safePath)), shellQuote(raw), shellQuote(safePath),
+			)
+
+		case "script":
+			interpreter, _ := seg.Data["interpreter"].(string)
+			raw, _ := seg.Data["body"].(string)
+			if raw == "" {
+				return resp, fmt.Errorf("script 片段缺少 body")
+			}
+			body, err := base64.StdEncoding.DecodeString(raw)
+			if err != nil {
+				return resp, fmt.Errorf("script 片段 base64 解码失败: %v", err)
+			}
+			path, err := writeTempScript(interpreter, body)
+			if err != nil {
+				return resp, fmt.Errorf("落地脚本失败: %v", err)
+			}
+			resp.Command = scriptInvocation(interpreter, path)
+
+		case "markdown":
+			raw, _ := seg.Data["body"].(string)
+			if raw == "" {
+				continue
+			}
+			body, err := base64.StdEncoding.DecodeString(raw)
+			if err != nil {
+				return resp, fmt.Errorf("markdown 片段 base64 解码失败: %v", err)
+			}
+			resp.FinalReport = string(body)
+		}
+	}
+
+	resp.Thought = strings.Join(thoughts, "\n")
+	return resp, nil
+}
+
+// attachmentDir 是 file 片段允许落盘的沙箱根目录，固定在用户私有的 ~/.deepsentry 下
+func attachmentDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".deepsentry", "attachments"), nil
+}
+
+// sandboxAttachmentPath 把 file 片段里 LLM 给出的 path 收敛成沙箱目录下的一个安全路径：
+// 只取 filepath.Clean 之后的 basename，绝对路径、"../" 穿越这些都在取 basename 的过程中
+// 被丢掉，不需要再单独识别和拒绝
+func sandboxAttachmentPath(rawPath string) (string, error) {
+	base := filepath.Base(filepath.Clean(strings.TrimSpace(rawPath)))
+	if base == "" || base == "." || base == ".." || base == string(filepath.Separator) {
+		return "", fmt.Errorf("无法从 %q 收敛出合法的文件名", rawPath)
+	}
+
+	dir, err := attachmentDir()
+	if err != nil {
+		return "", fmt.Errorf("定位沙箱目录失败: %v", err)
+	}
+	return filepath.Join(dir, base), nil
+}
+
+// shellQuote 把一个参数用 POSIX 单引号安全地引起来，供拼接 file 片段的落盘 Command 使用；
+// 和 executor.shellQuoteSingle 是同一个需求，但两边分属不同的包，没有共用的 shell 工具包
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// scriptExt/scriptInvocation 依据 interpreter 决定临时脚本的后缀与调用方式，未知 interpreter 一律当成 bash 处理
+var scriptExt = map[string]string{
+	"powershell": ".ps1",
+	"cmd":        ".bat",
+	"bash":       ".sh",
+}
+
+// writeTempScript 把脚本正文写到一个临时文件里，返回的路径交给 scriptInvocation 拼出可执行命令
+func writeTempScript(interpreter string, body []byte) (string, error) {
+	ext, ok := scriptExt[interpreter]
+	if !ok {
+		ext = ".sh"
+	}
+	f, err := os.CreateTemp("", "deepsentry-script-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(body); err != nil {
+		return "", err
+	}
+	if err := f.Chmod(0700); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// scriptInvocation 拼出按路径调用落地脚本的命令，而不是把脚本内容内联进 JSON 字符串
+func scriptInvocation(interpreter, path string) string {
+	switch interpreter {
+	case "powershell":
+		return fmt.Sprintf("powershell -NoProfile -ExecutionPolicy Bypass -File %s", path)
+	case "cmd":
+		return fmt.Sprintf("cmd /C %s", path)
+	default: // bash 及未知 interpreter
+		return fmt.Sprintf("bash %s", path)
+	}
+}
+
 func inferThoughtFromCommand(cmd string) string {
 	if strings.HasPrefix(cmd, "upload") {
 		return "正在上传文件到目标主机..."
@@ -334,52 +573,72 @@ func extractCommandString(jsonStr string) (string, bool) {
 	return "", false
 }
 
-// callLLM 统一调用大模型接口
-func callLLM(apiKey string, messages []Message) (string, error) {
-	reqBody := ChatRequest{
-		Model:       config.GlobalConfig.ModelName,
-		Messages:    messages,
-		Stream:      false,
-		Temperature: 0.1, // Temperature 设低一点，让 AI 输出更稳定
-	}
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
-	}
+// getRouter 懒加载并缓存一个按 config.GlobalConfig.Providers 构造的 provider.Router；
+// Providers 为空时退回一个只有单个 openai provider 的 Router，字段取自旧版扁平的
+// ApiURL/ApiKey/ModelName/Temperature，保证没有配置 providers 列表的老用户不受影响
+func getRouter() (*provider.Router, error) {
+	routerMu.Lock()
+	defer routerMu.Unlock()
 
-	req, err := http.NewRequest("POST", config.GlobalConfig.ApiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
+	if cachedRouter != nil {
+		return cachedRouter, nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	if apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+	specs := toProviderSpecs(config.GlobalConfig.Providers)
+	if len(specs) == 0 {
+		specs = []provider.Spec{{
+			Name:        "openai",
+			Role:        "primary",
+			ApiURL:      config.GlobalConfig.ApiURL,
+			ApiKey:      config.GlobalConfig.ApiKey,
+			ModelName:   config.GlobalConfig.ModelName,
+			Temperature: config.GlobalConfig.Temperature,
+		}}
 	}
 
-	client := &http.Client{Timeout: 300 * time.Second}
-	resp, err := client.Do(req)
+	r, err := provider.NewRouter(specs)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	cachedRouter = r
+	return r, nil
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API Error %d: %s", resp.StatusCode, string(body))
+func toProviderSpecs(specs []config.ProviderSpec) []provider.Spec {
+	out := make([]provider.Spec, len(specs))
+	for i, s := range specs {
+		out[i] = provider.Spec{
+			Name:        s.Name,
+			Role:        s.Role,
+			ApiURL:      s.ApiURL,
+			ApiKey:      s.ApiKey,
+			ModelName:   s.ModelName,
+			Temperature: s.Temperature,
+		}
 	}
+	return out
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+func toProviderMessages(messages []Message) []provider.Message {
+	out := make([]provider.Message, len(messages))
+	for i, m := range messages {
+		out[i] = provider.Message{Role: m.Role, Content: m.Content}
 	}
+	return out
+}
 
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("Parse Error: %v", err)
+// appendUsageReport 在任务结束时把 Router 里每个用过的 Provider 的 token/成本统计
+// 追加到 FinalReport 末尾，让分析师知道这次调查花了多少钱、是否触发过 failover
+func appendUsageReport(resp AgentResponse) AgentResponse {
+	if !resp.IsFinished {
+		return resp
+	}
+	router, err := getRouter()
+	if err != nil {
+		return resp
 	}
-	if len(chatResp.Choices) > 0 {
-		return chatResp.Choices[0].Message.Content, nil
+	if usage := router.UsageReport(); usage != "" {
+		resp.FinalReport += "\n\n📊 Provider 用量:\n" + usage
 	}
-	return "", errors.New("empty response")
+	return resp
 }
@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"ai-edr/internal/analyzer/recorder"
+	"ai-edr/internal/collector"
+	"path/filepath"
+	"testing"
+)
+
+// buildFixtureRecording 录制一份两步的 Agent 交互：第一步下发一条低危命令，第二步拿到
+// 执行结果后直接给出最终报告。产出的文件和 deepsentryd 真正跑出来的录制格式完全一样，
+// 只是手写了 RawResponse，不用真的连大模型
+func buildFixtureRecording(t *testing.T, path string) {
+	t.Helper()
+
+	rec, err := recorder.New(path)
+	if err != nil {
+		t.Fatalf("创建录制文件失败: %v", err)
+	}
+
+	idx1, err := rec.RecordLLM(
+		ChatRequest{},
+		AgentResponse{Thought: "先看看系统时间", Command: "date", RiskLevel: "low"},
+		`{"thought":"先看看系统时间","command":"date"}`,
+	)
+	if err != nil {
+		t.Fatalf("录制第 1 步失败: %v", err)
+	}
+	if err := rec.RecordExec(idx1, "date", "Mon Jan 1 00:00:00 UTC 2026", "low", "安全操作"); err != nil {
+		t.Fatalf("补全第 1 步执行结果失败: %v", err)
+	}
+
+	idx2, err := rec.RecordLLM(
+		ChatRequest{},
+		AgentResponse{Thought: "任务完成", IsFinished: true, FinalReport: "系统时间正常，未发现异常"},
+		`{"thought":"任务完成","is_finished":true,"final_report":"系统时间正常，未发现异常"}`,
+	)
+	if err != nil {
+		t.Fatalf("录制第 2 步失败: %v", err)
+	}
+	if err := rec.RecordExec(idx2, "", "", "", ""); err != nil {
+		t.Fatalf("补全第 2 步执行结果失败: %v", err)
+	}
+
+	if err := rec.Close(); err != nil {
+		t.Fatalf("关闭录制文件失败: %v", err)
+	}
+}
+
+// TestRunAgentStepReplaysFixtureRecording 验证录制文件能驱动 RunAgentStep 跑完一整轮
+// Agent 循环并得到预期的最终 AgentResponse，不依赖网络、不真的调用 LLM。后续遇到 LLM
+// 返回畸形/意外格式的真实案例，照着 buildFixtureRecording 的样子录一份新 fixture 加进来即可
+func TestRunAgentStepReplaysFixtureRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.jsonl")
+	buildFixtureRecording(t, path)
+
+	client, err := NewReplayClient(path)
+	if err != nil {
+		t.Fatalf("加载录制文件失败: %v", err)
+	}
+	restore := SetLLMClient(client)
+	defer restore()
+
+	var history []Message
+	var final AgentResponse
+
+	for i := 0; i < client.Len(); i++ {
+		resp, err := RunAgentStep(collector.SystemContext{}, &history)
+		if err != nil {
+			t.Fatalf("第 %d 步回放失败: %v", i+1, err)
+		}
+		final = resp
+		history = append(history, Message{Role: "assistant", Content: resp.Thought})
+		if resp.Command != "" {
+			history = append(history, Message{Role: "user", Content: "命令: " + resp.Command})
+		}
+		if resp.IsFinished {
+			break
+		}
+	}
+
+	if final.Command != "" {
+		t.Fatalf("最后一步不应再带命令，实际: %q", final.Command)
+	}
+	if !final.IsFinished {
+		t.Fatalf("期望最后一步 IsFinished=true，实际: %+v", final)
+	}
+	if final.FinalReport != "系统时间正常，未发现异常" {
+		t.Fatalf("最终报告不符: %q", final.FinalReport)
+	}
+}
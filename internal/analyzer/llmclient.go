@@ -0,0 +1,33 @@
+package analyzer
+
+import "context"
+
+// LLMClient 是 RunAgentStep 调用大模型的可插拔接口：生产环境下默认是 httpLLMClient
+// （转发给 getRouter() 构造的 provider.Router，按 providers 列表做多后端 failover），
+// 录制回放场景下可以用 SetLLMClient 换成 NewReplayClient 返回的 *ReplayClient，这样
+// 重跑一段调查只读录制文件，不用真的打 API
+type LLMClient interface {
+	Call(apiKey string, messages []Message) (string, error)
+}
+
+// activeLLMClient 是当前生效的 LLM 客户端
+var activeLLMClient LLMClient = httpLLMClient{}
+
+// SetLLMClient 替换全局生效的 LLM 客户端，返回的 restore 用于换完之后还原回之前的客户端
+func SetLLMClient(c LLMClient) (restore func()) {
+	prev := activeLLMClient
+	activeLLMClient = c
+	return func() { activeLLMClient = prev }
+}
+
+// httpLLMClient 是默认实现：忽略传入的 apiKey（每个 provider 的 key 已经在 Router 里），
+// 转发给 getRouter() 按 providers 列表编排出的 Router
+type httpLLMClient struct{}
+
+func (httpLLMClient) Call(apiKey string, messages []Message) (string, error) {
+	router, err := getRouter()
+	if err != nil {
+		return "", err
+	}
+	return router.Chat(context.Background(), toProviderMessages(messages))
+}
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// anthropicCostPerMillion 见 openAICostPerMillion 的说明：只用于最终报告里的数量级估算
+const anthropicCostPerMillion = 15.0
+
+// anthropicChatRequest 对应 Anthropic Messages API（POST /v1/messages）：system 提示独立于
+// messages 数组之外，这是它与 OpenAI /v1/chat/completions 最主要的形状差异
+type anthropicChatRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicChatMsg `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+}
+
+type anthropicChatMsg struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicChatResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicProvider 实现 Anthropic Messages API
+type anthropicProvider struct {
+	spec Spec
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+func newAnthropicProvider(spec Spec) *anthropicProvider {
+	if spec.ApiURL == "" {
+		spec.ApiURL = "https://api.anthropic.com/v1/messages"
+	}
+	return &anthropicProvider{spec: spec}
+}
+
+func (p *anthropicProvider) Name() string           { return providerLabel("anthropic", p.spec) }
+func (p *anthropicProvider) SupportsStreaming() bool { return false }
+func (p *anthropicProvider) Usage() Usage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.usage
+}
+
+func (p *anthropicProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	system, chatMsgs := splitSystemPrompt(messages)
+
+	reqBody := anthropicChatRequest{
+		Model:       p.spec.ModelName,
+		System:      system,
+		Messages:    chatMsgs,
+		MaxTokens:   4096,
+		Temperature: p.spec.Temperature,
+	}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.spec.ApiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.spec.ApiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	client := &http.Client{Timeout: 300 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		return "", &httpStatusError{status: resp.StatusCode, body: string(body)}
+	}
+
+	var chatResp anthropicChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", fmt.Errorf("解析 Anthropic 响应失败: %v", err)
+	}
+
+	p.mu.Lock()
+	p.usage.Calls++
+	p.usage.PromptTokens += chatResp.Usage.InputTokens
+	p.usage.CompletionTokens += chatResp.Usage.OutputTokens
+	p.usage.EstimatedCostUSD += float64(chatResp.Usage.InputTokens+chatResp.Usage.OutputTokens) / 1_000_000 * anthropicCostPerMillion
+	p.mu.Unlock()
+
+	for _, block := range chatResp.Content {
+		if block.Type == "text" {
+			return block.Text, nil
+		}
+	}
+	return "", fmt.Errorf("Anthropic 响应不含文本内容")
+}
+
+// splitSystemPrompt 把 messages 里所有 system 消息按出现顺序用空行拼接后单独传给
+// Anthropic 的 system 字段，其余原样作为 user/assistant 轮次传给 messages 数组
+func splitSystemPrompt(messages []Message) (string, []anthropicChatMsg) {
+	var system string
+	chatMsgs := make([]anthropicChatMsg, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == "system" {
+			if system != "" {
+				system += "\n\n"
+			}
+			system += m.Content
+			continue
+		}
+		chatMsgs = append(chatMsgs, anthropicChatMsg{Role: m.Role, Content: m.Content})
+	}
+	return system, chatMsgs
+}
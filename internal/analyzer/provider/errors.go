@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// httpStatusError 是某个 Provider 的 HTTP 调用返回非 200 状态码时的统一错误类型，
+// Router 靠 StatusCode() 判断是否值得 failover/退避重试
+type httpStatusError struct {
+	status int
+	body   string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.status, e.body)
+}
+
+func (e *httpStatusError) StatusCode() int { return e.status }
+
+// isRetryable 判断一次调用失败是否值得退避重试/切换到下一个 Provider：
+// 429（限流）、5xx（服务端错误）、超时或网络层错误都算，4xx（除 429 外，多半是配置/鉴权问题）不算
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status == 429 || statusErr.status >= 500
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// geminiCostPerMillion 见 openAICostPerMillion 的说明：只用于最终报告里的数量级估算
+const geminiCostPerMillion = 3.5
+
+// geminiChatRequest 对应 Google Generative Language API 的 generateContent：没有 role 区分
+// system/user/assistant，统一按 "user"/"model" 的 contents 数组表达，system 提示作为
+// systemInstruction 单独传入
+type geminiChatRequest struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+	GenerationConfig  geminiGenConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiGenConfig struct {
+	Temperature float64 `json:"temperature"`
+}
+
+type geminiChatResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiProvider 实现 Google Gemini 的 generateContent 接口
+type geminiProvider struct {
+	spec Spec
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+func newGeminiProvider(spec Spec) *geminiProvider {
+	if spec.ApiURL == "" {
+		spec.ApiURL = fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", spec.ModelName)
+	}
+	return &geminiProvider{spec: spec}
+}
+
+func (p *geminiProvider) Name() string           { return providerLabel("gemini", p.spec) }
+func (p *geminiProvider) SupportsStreaming() bool { return false }
+func (p *geminiProvider) Usage() Usage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.usage
+}
+
+func (p *geminiProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	system, contents := toGeminiContents(messages)
+
+	reqBody := geminiChatRequest{
+		Contents:         contents,
+		GenerationConfig: geminiGenConfig{Temperature: p.spec.Temperature},
+	}
+	if system != "" {
+		reqBody.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	// key 走 x-goog-api-key 头而不是拼进 URL：DNS/dial/TLS/超时这类传输层失败会被 Go
+	// 包装成 *url.Error，其 Error() 原样带着请求的 URL，一路传到 chatChain 的错误信息、
+	// daemon 的 error SSE 事件、"providers test" 的终端输出，URL 里带 key 就是明文泄露
+	req, err := http.NewRequestWithContext(ctx, "POST", p.spec.ApiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", p.spec.ApiKey)
+
+	client := &http.Client{Timeout: 300 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		return "", &httpStatusError{status: resp.StatusCode, body: string(body)}
+	}
+
+	var chatResp geminiChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", fmt.Errorf("解析 Gemini 响应失败: %v", err)
+	}
+	if len(chatResp.Candidates) == 0 || len(chatResp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("Gemini 响应为空")
+	}
+
+	p.mu.Lock()
+	p.usage.Calls++
+	p.usage.PromptTokens += chatResp.UsageMetadata.PromptTokenCount
+	p.usage.CompletionTokens += chatResp.UsageMetadata.CandidatesTokenCount
+	p.usage.EstimatedCostUSD += float64(chatResp.UsageMetadata.PromptTokenCount+chatResp.UsageMetadata.CandidatesTokenCount) / 1_000_000 * geminiCostPerMillion
+	p.mu.Unlock()
+
+	return chatResp.Candidates[0].Content.Parts[0].Text, nil
+}
+
+// toGeminiContents 把 system 角色的消息摘出来单独返回，其余按 Gemini 的 "model" 角色名
+// （而不是 OpenAI 风格的 "assistant"）重新打包成 contents 数组
+func toGeminiContents(messages []Message) (string, []geminiContent) {
+	var system string
+	contents := make([]geminiContent, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == "system" {
+			if system != "" {
+				system += "\n\n"
+			}
+			system += m.Content
+			continue
+		}
+		role := m.Role
+		if role == "assistant" {
+			role = "model"
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+	return system, contents
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ollamaChatRequest/ollamaChatResponse 对应本地 Ollama 的 POST /api/chat：没有 API Key，
+// 模型名对应的是已经用 `ollama pull` 拉到本地的模型，不会产生任何出网流量——这是让
+// DeepSentry 能在隔离网络的 IR 现场使用的关键
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIChatMsg `json:"messages"` // 和 OpenAI 同形（role/content），Ollama 原样兼容
+	Stream   bool            `json:"stream"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+}
+
+type ollamaChatResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// ollamaProvider 实现本地 Ollama 后端
+type ollamaProvider struct {
+	spec Spec
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+func newOllamaProvider(spec Spec) *ollamaProvider {
+	if spec.ApiURL == "" {
+		spec.ApiURL = "http://localhost:11434/api/chat"
+	}
+	return &ollamaProvider{spec: spec}
+}
+
+func (p *ollamaProvider) Name() string           { return providerLabel("ollama", p.spec) }
+func (p *ollamaProvider) SupportsStreaming() bool { return false }
+func (p *ollamaProvider) Usage() Usage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.usage
+}
+
+func (p *ollamaProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	reqBody := ollamaChatRequest{
+		Model:    p.spec.ModelName,
+		Messages: toOpenAIMessages(messages),
+		Stream:   false,
+		Options:  ollamaOptions{Temperature: p.spec.Temperature},
+	}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.spec.ApiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 300 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		return "", &httpStatusError{status: resp.StatusCode, body: string(body)}
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", fmt.Errorf("解析 Ollama 响应失败: %v", err)
+	}
+
+	p.mu.Lock()
+	p.usage.Calls++
+	p.usage.PromptTokens += chatResp.PromptEvalCount
+	p.usage.CompletionTokens += chatResp.EvalCount
+	// 本地模型不计费，EstimatedCostUSD 保持为 0
+	p.mu.Unlock()
+
+	return chatResp.Message.Content, nil
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// openAIChatRequest/openAIChatResponse 是 OpenAI /v1/chat/completions 的精简结构，
+// 请求部分与 analyzer.ChatRequest 保持同形，供所有 OpenAI 兼容网关（包括自建的）复用
+type openAIChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIChatMsg `json:"messages"`
+	Stream      bool            `json:"stream"`
+	Temperature float64         `json:"temperature"`
+}
+
+type openAIChatMsg struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// openAICostPerMillion 是一个保守的估算单价（美元/百万 token），只用来在最终报告里给出数量级参考，
+// 不是计费依据
+const openAICostPerMillion = 5.0
+
+// openAIProvider 是默认的 OpenAI 兼容实现：原有的 callLLM 就是这个形状，ApiURL 允许指向
+// 任何声称兼容 OpenAI /v1/chat/completions 协议的网关（国内代理商、vLLM/oneapi 自建网关等）
+type openAIProvider struct {
+	spec Spec
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+func newOpenAIProvider(spec Spec) *openAIProvider {
+	return &openAIProvider{spec: spec}
+}
+
+func (p *openAIProvider) Name() string           { return providerLabel("openai", p.spec) }
+func (p *openAIProvider) SupportsStreaming() bool { return true }
+func (p *openAIProvider) Usage() Usage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.usage
+}
+
+func (p *openAIProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	reqBody := openAIChatRequest{
+		Model:       p.spec.ModelName,
+		Messages:    toOpenAIMessages(messages),
+		Stream:      false,
+		Temperature: p.spec.Temperature,
+	}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.spec.ApiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.spec.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.spec.ApiKey)
+	}
+
+	client := &http.Client{Timeout: 300 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		return "", &httpStatusError{status: resp.StatusCode, body: string(body)}
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", fmt.Errorf("解析 OpenAI 响应失败: %v", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("OpenAI 响应为空")
+	}
+
+	p.mu.Lock()
+	p.usage.Calls++
+	p.usage.PromptTokens += chatResp.Usage.PromptTokens
+	p.usage.CompletionTokens += chatResp.Usage.CompletionTokens
+	p.usage.EstimatedCostUSD += float64(chatResp.Usage.PromptTokens+chatResp.Usage.CompletionTokens) / 1_000_000 * openAICostPerMillion
+	p.mu.Unlock()
+
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+func toOpenAIMessages(messages []Message) []openAIChatMsg {
+	out := make([]openAIChatMsg, len(messages))
+	for i, m := range messages {
+		out[i] = openAIChatMsg{Role: m.Role, Content: m.Content}
+	}
+	return out
+}
+
+// providerLabel 拼出 Name() 的展示名：优先用配置里的 model_name，没有就退回 kind
+func providerLabel(kind string, spec Spec) string {
+	if spec.ModelName != "" {
+		return fmt.Sprintf("%s:%s", kind, spec.ModelName)
+	}
+	return kind
+}
@@ -0,0 +1,70 @@
+// Package provider 把"调用哪个大模型"从 analyzer 的主循环里解耦出来：Provider 是单个
+// 后端（OpenAI 兼容接口、Anthropic Messages API、Google Gemini、本地 Ollama）的最小抽象，
+// Router（见 router.go）按 config.yaml 里声明的顺序和角色把它们编排成一个支持失败自动
+// 切换的整体。之所以不直接放进 analyzer 包：analyzer 需要依赖 Router 来发起调用，
+// 这里反过来不感知 analyzer.Message/AgentResponse 之类的类型，避免循环依赖（与 recorder
+// 包同样的考虑）
+package provider
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message 是发给/收到的单条对话消息，字段含义与 analyzer.Message 一致，
+// 调用方在跨包边界处做一次字段对字段的转换
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Usage 记录一个 Provider 累计消耗的 token 与估算成本，供 Router 汇总进最终报告
+type Usage struct {
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	EstimatedCostUSD float64
+}
+
+// Provider 是单个 LLM 后端的最小抽象
+type Provider interface {
+	// Chat 发送完整的对话历史，返回模型的文本回复
+	Chat(ctx context.Context, messages []Message) (string, error)
+	// Name 是这个 Provider 实例的标识，用于日志、failover 提示和 usage 汇总
+	Name() string
+	// SupportsStreaming 标记该后端是否有 SSE 流式接口；目前只有 OpenAI 兼容后端返回 true。
+	// Router 不读取这个标记，Chat/ChatSummarize 一律走非流式调用
+	SupportsStreaming() bool
+}
+
+// UsageReporter 是 Provider 的可选扩展接口：实现了它的 Provider 能把累计消耗暴露给 Router
+type UsageReporter interface {
+	Usage() Usage
+}
+
+// Spec 描述配置文件里的一个 provider 条目，对应 config.ProviderSpec；
+// 放在这里而不是直接依赖 config 包，同样是为了避免循环依赖
+type Spec struct {
+	Name        string // openai | anthropic | gemini | ollama，决定走哪个具体实现
+	Role        string // primary | fallback | summarizer
+	ApiURL      string
+	ApiKey      string
+	ModelName   string
+	Temperature float64
+}
+
+// New 按 Spec.Name 构造对应的具体 Provider 实现
+func New(spec Spec) (Provider, error) {
+	switch spec.Name {
+	case "openai", "":
+		return newOpenAIProvider(spec), nil
+	case "anthropic":
+		return newAnthropicProvider(spec), nil
+	case "gemini":
+		return newGeminiProvider(spec), nil
+	case "ollama":
+		return newOllamaProvider(spec), nil
+	default:
+		return nil, fmt.Errorf("未知的 provider: %s", spec.Name)
+	}
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxRetriesPerProvider 是单个 Provider 在放弃、转给链上下一个 Provider 之前的重试次数
+const maxRetriesPerProvider = 3
+
+// initialBackoff 是第一次重试前的等待时长，此后每次翻倍（指数退避）
+const initialBackoff = 500 * time.Millisecond
+
+// Router 把 config.yaml 里按 role 声明的一组 Provider 编排成一条调用链：主推理循环走
+// primary（按声明顺序依次尝试，每个失败后按 429/5xx/超时做指数退避重试，仍失败才换下一个），
+// compressHistory 这类对模型质量要求更低的场景可以走 summarizer（通常指向一个本地 Ollama 模型）
+type Router struct {
+	primary    []Provider
+	fallback   []Provider
+	summarizer Provider
+}
+
+// NewRouter 按 role 把 specs 分组成 Router；至少需要一个 role=primary（或未填 role，默认当 primary）
+func NewRouter(specs []Spec) (*Router, error) {
+	r := &Router{}
+	for _, spec := range specs {
+		p, err := New(spec)
+		if err != nil {
+			return nil, fmt.Errorf("构造 provider %q 失败: %v", spec.Name, err)
+		}
+		switch spec.Role {
+		case "fallback":
+			r.fallback = append(r.fallback, p)
+		case "summarizer":
+			r.summarizer = p
+		default: // "primary" 或空
+			r.primary = append(r.primary, p)
+		}
+	}
+	if len(r.primary) == 0 {
+		return nil, fmt.Errorf("providers 列表里至少需要一个 role=primary（或不填 role）的条目")
+	}
+	return r, nil
+}
+
+// Chat 依次尝试 primary 链，全部失败后依次尝试 fallback 链
+func (r *Router) Chat(ctx context.Context, messages []Message) (string, error) {
+	return chatChain(ctx, append(append([]Provider{}, r.primary...), r.fallback...), messages)
+}
+
+// ChatSummarize 优先用 summarizer provider（没配置则退回 Chat 的主/备链），供 compressHistory
+// 这类对着一大段历史做摘要、但不需要前沿模型质量的场景使用，从而把真正的 token 预算留给主推理循环
+func (r *Router) ChatSummarize(ctx context.Context, messages []Message) (string, error) {
+	if r.summarizer != nil {
+		if resp, err := chatWithRetry(ctx, r.summarizer, messages); err == nil {
+			return resp, nil
+		}
+	}
+	return r.Chat(ctx, messages)
+}
+
+// All 返回 Router 持有的全部 Provider（primary + fallback + summarizer），供
+// UsageReport 汇总 token/成本
+func (r *Router) All() []Provider {
+	all := make([]Provider, 0, len(r.primary)+len(r.fallback)+1)
+	all = append(all, r.primary...)
+	all = append(all, r.fallback...)
+	if r.summarizer != nil {
+		all = append(all, r.summarizer)
+	}
+	return all
+}
+
+// UsageReport 把每个实现了 UsageReporter 的 Provider 的累计 token/成本拼成几行文本，
+// 供 RunAgentStep 在任务完成时附到 final_report 后面
+func (r *Router) UsageReport() string {
+	var b strings.Builder
+	for _, p := range r.All() {
+		reporter, ok := p.(UsageReporter)
+		if !ok {
+			continue
+		}
+		u := reporter.Usage()
+		if u.Calls == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %d 次调用, %d+%d tokens, 约 $%.4f\n",
+			p.Name(), u.Calls, u.PromptTokens, u.CompletionTokens, u.EstimatedCostUSD)
+	}
+	return b.String()
+}
+
+// chatChain 按顺序尝试 providers，每个都经过 chatWithRetry 的退避重试；全部失败时返回
+// 拼接了每个 provider 失败原因的汇总错误，方便排查是哪一环出了问题
+func chatChain(ctx context.Context, providers []Provider, messages []Message) (string, error) {
+	var failures []string
+	for _, p := range providers {
+		resp, err := chatWithRetry(ctx, p, messages)
+		if err == nil {
+			return resp, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", p.Name(), err))
+	}
+	return "", fmt.Errorf("所有 provider 均调用失败:\n%s", strings.Join(failures, "\n"))
+}
+
+// chatWithRetry 对单个 Provider 做指数退避重试：仅当 isRetryable(err) 判定值得重试
+// （429/5xx/超时）时才继续，其余错误（鉴权失败等）立刻放弃，不浪费重试预算
+func chatWithRetry(ctx context.Context, p Provider, messages []Message) (string, error) {
+	var lastErr error
+	backoff := initialBackoff
+
+	for attempt := 0; attempt <= maxRetriesPerProvider; attempt++ {
+		resp, err := p.Chat(ctx, messages)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		if !isRetryable(err) || attempt == maxRetriesPerProvider {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		backoff *= 2
+	}
+
+	return "", lastErr
+}
+
+// Ping 用一条最小的探活消息测试 Provider 是否可达，供 `deepsentry providers test` 使用
+func Ping(ctx context.Context, p Provider) error {
+	_, err := p.Chat(ctx, []Message{{Role: "user", Content: "ping"}})
+	return err
+}
@@ -0,0 +1,157 @@
+// Package recorder 实现 HAR 风格的 Agent 单步交互录制：把每一次 LLM 交互
+// （发给模型的完整请求、原始响应文本、解析后的结构化结果）与紧随其后的命令执行结果
+// （实际执行的命令、stdout、security.CheckRisk 的风险判定）合并成一条 JSON 记录追加写入
+// 文件，供 `deepsentry replay` 和回归测试按录制顺序回放，而不必真的调用大模型。
+//
+// 记录格式特意不依赖 analyzer 包的具体类型（Request/Parsed 用 json.RawMessage 承载），
+// 避免 analyzer 反过来 import recorder 时出现循环依赖。
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Step 是单次 Agent 交互的完整录制记录
+type Step struct {
+	Index       int             `json:"index"`
+	Ts          time.Time       `json:"ts"`
+	Request     json.RawMessage `json:"request,omitempty"`     // 发给 LLM 的完整请求（ChatRequest）
+	RawResponse string          `json:"rawResponse,omitempty"` // LLM 原始响应文本
+	Parsed      json.RawMessage `json:"parsed,omitempty"`      // 解析后的 AgentResponse
+	Command     string          `json:"command,omitempty"`     // 本步实际执行的命令，空表示没有命令（如 final_report）
+	Stdout      string          `json:"stdout,omitempty"`      // 命令输出
+	Risk        string          `json:"risk,omitempty"`        // security.CheckRisk 给出的风险等级
+	Reason      string          `json:"reason,omitempty"`      // 风险判定依据
+}
+
+// Recorder 把一系列 Step 追加写入一个 JSON Lines 文件。一步交互分两阶段完成：
+// RecordLLM 先记下请求/响应，命令真正执行完之后再用 RecordExec 补全并落盘
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	index   int
+	pending map[int]*Step
+}
+
+// New 以追加模式打开（或创建）path 处的录制文件
+func New(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开录制文件失败: %v", err)
+	}
+	return &Recorder{file: f, pending: make(map[int]*Step)}, nil
+}
+
+// RecordLLM 记录一次 LLM 交互的请求与响应，返回的 index 用来之后调用 RecordExec 补全执行结果
+func (r *Recorder) RecordLLM(request, parsed interface{}, rawResponse string) (int, error) {
+	reqBytes, err := json.Marshal(request)
+	if err != nil {
+		return 0, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	parsedBytes, err := json.Marshal(parsed)
+	if err != nil {
+		return 0, fmt.Errorf("序列化响应失败: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.index++
+	idx := r.index
+	r.pending[idx] = &Step{
+		Index:       idx,
+		Ts:          time.Now(),
+		Request:     reqBytes,
+		RawResponse: rawResponse,
+		Parsed:      parsedBytes,
+	}
+	return idx, nil
+}
+
+// LastIndex 返回最近一次 RecordLLM 分配的步骤编号
+func (r *Recorder) LastIndex() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.index
+}
+
+// RecordExec 用实际执行结果补全 RecordLLM 返回的那个步骤，并立即落盘
+func (r *Recorder) RecordExec(index int, command, stdout, risk, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	step, ok := r.pending[index]
+	if !ok {
+		return fmt.Errorf("录制步骤不存在（已落盘或从未记录）: %d", index)
+	}
+	step.Command, step.Stdout, step.Risk, step.Reason = command, stdout, risk, reason
+	delete(r.pending, index)
+	return r.writeLocked(step)
+}
+
+// Flush 把所有尚未调用 RecordExec 的步骤（比如模型最后一步只给了 final_report、没有命令要执行）直接落盘
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	indexes := make([]int, 0, len(r.pending))
+	for idx := range r.pending {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	for _, idx := range indexes {
+		if err := r.writeLocked(r.pending[idx]); err != nil {
+			return err
+		}
+		delete(r.pending, idx)
+	}
+	return nil
+}
+
+func (r *Recorder) writeLocked(step *Step) error {
+	line, err := json.Marshal(step)
+	if err != nil {
+		return fmt.Errorf("序列化录制记录失败: %v", err)
+	}
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入录制文件失败: %v", err)
+	}
+	return nil
+}
+
+// Close 落盘所有尚未写出的步骤并关闭底层文件句柄
+func (r *Recorder) Close() error {
+	if err := r.Flush(); err != nil {
+		return err
+	}
+	return r.file.Close()
+}
+
+// Load 按 Index 顺序读取一个录制文件里的全部 Step，供 `deepsentry replay` 与测试 harness 使用
+func Load(path string) ([]Step, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取录制文件失败: %v", err)
+	}
+
+	var steps []Step
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var step Step
+		if err := json.Unmarshal([]byte(line), &step); err != nil {
+			return nil, fmt.Errorf("解析录制记录失败: %v", err)
+		}
+		steps = append(steps, step)
+	}
+
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Index < steps[j].Index })
+	return steps, nil
+}
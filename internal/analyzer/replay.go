@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"ai-edr/internal/analyzer/recorder"
+	"fmt"
+)
+
+// ReplayClient 实现 LLMClient，按录制文件里的顺序依次回放 RawResponse，
+// 用于在不消耗 token、不依赖网络的前提下针对真实遇到过的畸形 JSON 做回归测试
+type ReplayClient struct {
+	steps []recorder.Step
+	pos   int
+}
+
+// NewReplayClient 加载一份由 recorder.Recorder 产出的录制文件
+func NewReplayClient(path string) (*ReplayClient, error) {
+	steps, err := recorder.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayClient{steps: steps}, nil
+}
+
+// Call 实现 LLMClient：忽略传入的 apiKey/messages，按录制顺序依次返回 RawResponse
+func (c *ReplayClient) Call(apiKey string, messages []Message) (string, error) {
+	if c.pos >= len(c.steps) {
+		return "", fmt.Errorf("回放录制文件已到末尾（共 %d 步）", len(c.steps))
+	}
+	resp := c.steps[c.pos].RawResponse
+	c.pos++
+	return resp, nil
+}
+
+// Reset 把回放位置归零，便于同一份录制反复驱动多次测试
+func (c *ReplayClient) Reset() { c.pos = 0 }
+
+// Len 返回录制文件里的总步数
+func (c *ReplayClient) Len() int { return len(c.steps) }
+
+// Steps 返回原始录制步骤，供 `deepsentry replay` 之类需要展示命令/输出的场景直接读取
+func (c *ReplayClient) Steps() []recorder.Step { return c.steps }
@@ -0,0 +1,309 @@
+package analyzer
+
+import (
+	"ai-edr/internal/collector"
+	"ai-edr/internal/config"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AgentPartial 是 RunAgentStepStream 在单步思考过程中持续推送的增量状态
+// Done 为 true 时流已结束：Err 非空表示异常终止（含 ctx 取消），否则 Final 是权威的最终结果
+type AgentPartial struct {
+	Thought   string
+	Command   string
+	RiskLevel string
+	Done      bool
+	Final     AgentResponse
+	Err       error
+}
+
+// chatStreamChunk 对应 OpenAI 风格 SSE 流里单条 "data: {...}" 消息的 choices[0].delta 增量
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// RunAgentStepStream 是 RunAgentStep 的流式版本：模型一边生成，thought/command/risk_level
+// 一边通过返回的 channel 增量推送给 TUI；ctx 取消时会立即中断底层请求，让一条看起来不对的命令
+// 在模型说完整句话之前就能被打断
+func RunAgentStepStream(ctx context.Context, sysCtx collector.SystemContext, history *[]Message) <-chan AgentPartial {
+	out := make(chan AgentPartial, 16)
+
+	go func() {
+		defer close(out)
+
+		apiKey := config.GlobalConfig.ApiKey
+		systemPrompt := buildSystemPrompt(sysCtx)
+
+		if len(*history) > 15 {
+			compressHistory(history)
+		}
+
+		messages := []Message{
+			{Role: "system", Content: systemPrompt},
+		}
+		messages = append(messages, *history...)
+
+		fragments, errCh := callLLMStream(ctx, apiKey, messages)
+
+		var fullBuf strings.Builder
+		parser := &streamParser{}
+		var lastThought, lastCommand, lastRisk string
+
+		emit := func() {
+			t, c, r := parser.Thought.String(), parser.Command.String(), parser.RiskLevel.String()
+			if t == lastThought && c == lastCommand && r == lastRisk {
+				return
+			}
+			lastThought, lastCommand, lastRisk = t, c, r
+			out <- AgentPartial{Thought: t, Command: c, RiskLevel: r}
+		}
+
+	readLoop:
+		for {
+			select {
+			case <-ctx.Done():
+				out <- AgentPartial{Done: true, Err: ctx.Err()}
+				return
+			case frag, ok := <-fragments:
+				if !ok {
+					break readLoop
+				}
+				fullBuf.WriteString(frag)
+				parser.feed(frag)
+				emit()
+			}
+		}
+
+		if err := <-errCh; err != nil {
+			out <- AgentPartial{Done: true, Err: err}
+			return
+		}
+
+		rawResp := fullBuf.String()
+		final := appendUsageReport(buildAgentResponse(rawResp))
+
+		if activeRecorder != nil {
+			if _, err := activeRecorder.RecordLLM(ChatRequest{Model: config.GlobalConfig.ModelName, Messages: messages, Stream: true, Temperature: 0.1}, final, rawResp); err != nil {
+				out <- AgentPartial{Done: true, Err: fmt.Errorf("录制 LLM 交互失败: %v", err)}
+				return
+			}
+		}
+
+		out <- AgentPartial{Done: true, Final: final}
+	}()
+
+	return out
+}
+
+// callLLMStream 以 SSE 流式方式调用大模型：逐行读取 "data: {...}"，把每个 delta.content
+// 片段推到返回的 fragments channel；ctx 取消会立即终止底层 HTTP 请求并通过 errCh 报告。
+// 注意：provider.Router 的多后端 failover 目前只覆盖 RunAgentStep 的非流式路径——
+// Anthropic/Gemini/Ollama 都没有和这里一致的 SSE 增量格式，流式路径仍然只认
+// config.GlobalConfig 里那一个 OpenAI 兼容端点
+func callLLMStream(ctx context.Context, apiKey string, messages []Message) (<-chan string, <-chan error) {
+	fragments := make(chan string)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(fragments)
+		defer close(errCh)
+
+		reqBody := ChatRequest{
+			Model:       config.GlobalConfig.ModelName,
+			Messages:    messages,
+			Stream:      true,
+			Temperature: 0.1,
+		}
+		jsonData, err := json.Marshal(reqBody)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", config.GlobalConfig.ApiURL, bytes.NewBuffer(jsonData))
+		if err != nil {
+			errCh <- err
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/event-stream")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+
+		client := &http.Client{Timeout: 300 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != 200 {
+			body, _ := io.ReadAll(resp.Body)
+			errCh <- fmt.Errorf("API Error %d: %s", resp.StatusCode, string(body))
+			return
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if payload == "[DONE]" {
+				return
+			}
+
+			var chunk chatStreamChunk
+			if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+				// 个别心跳/非标准行直接跳过，不中断整条流
+				continue
+			}
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+
+			select {
+			case fragments <- chunk.Choices[0].Delta.Content:
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errCh <- err
+		}
+	}()
+
+	return fragments, errCh
+}
+
+// streamParser 是 extractCommandString 的有状态版本：逐字符消费跨多个 chunk 到达的文本，
+// 记录当前是否处于某个字符串 / 转义序列内部，从而在 JSON 还未完整之前就能增量取出
+// thought/command/risk_level 三个顶层字段的值
+type streamParser struct {
+	depth      int  // 当前 {}/[] 嵌套深度，用于判断字符串是否出现在顶层 key 的位置
+	inString   bool // 是否处于某个字符串内部（key 或 value）
+	escaped    bool // 上一个字符是否是字符串内的反斜杠
+	isKey      bool // 当前字符串是 key 还是 value
+	awaitColon bool // 已闭合一个顶层 key，等待紧随其后的值
+	keyBuf     strings.Builder
+	activeKey  string // 最近一个顶层 key 的名字；只有它是我们关心的字段时，值才会被写进对应 buffer
+
+	Thought   strings.Builder
+	Command   strings.Builder
+	RiskLevel strings.Builder
+}
+
+// feed 消费一个新到达的文本片段，增量更新内部状态
+func (p *streamParser) feed(s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if p.inString {
+			if p.escaped {
+				p.escaped = false
+				if p.isKey {
+					p.writeUnescaped(&p.keyBuf, c)
+				} else if target := p.target(); target != nil {
+					p.writeUnescaped(target, c)
+				}
+				continue
+			}
+			switch c {
+			case '\\':
+				p.escaped = true
+			case '"':
+				p.inString = false
+				if p.isKey {
+					p.activeKey = p.keyBuf.String()
+					p.keyBuf.Reset()
+					p.awaitColon = true
+				}
+			default:
+				if p.isKey {
+					p.keyBuf.WriteByte(c)
+				} else if target := p.target(); target != nil {
+					target.WriteByte(c)
+				}
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			p.inString = true
+			if p.awaitColon {
+				// 紧跟在 "key": 之后的第一个引号，开启这个 key 的值字符串
+				p.isKey = false
+				p.awaitColon = false
+			} else if p.depth == 1 {
+				// 顶层对象里，不在等待值的位置出现的引号只能是下一个 key 的起点
+				p.isKey = true
+			} else {
+				// 嵌套结构内部的字符串，我们不关心具体归属
+				p.isKey = false
+			}
+		case c == '{' || c == '[':
+			p.depth++
+			// 值是对象/数组（如 final_report 可能是 object），不是我们能增量捕获的字符串
+			p.awaitColon = false
+		case c == '}' || c == ']':
+			p.depth--
+		case c == ':' || c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			// 分隔符/空白：保持 awaitColon 直到遇到值的首字符
+		default:
+			// 数字/布尔/null 等标量值的起始字符：这个 key 的值不是字符串，放弃捕获
+			p.awaitColon = false
+		}
+	}
+}
+
+// target 返回 activeKey 对应的累积 buffer；不是我们关心的字段时返回 nil
+func (p *streamParser) target() *strings.Builder {
+	switch p.activeKey {
+	case "thought":
+		return &p.Thought
+	case "command":
+		return &p.Command
+	case "risk_level":
+		return &p.RiskLevel
+	default:
+		return nil
+	}
+}
+
+// writeUnescaped 把一个转义序列还原成真实字符后写入 target，规则与 extractCommandString 保持一致
+func (p *streamParser) writeUnescaped(b *strings.Builder, c byte) {
+	switch c {
+	case '"', '\\', '/':
+		b.WriteByte(c)
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	default:
+		b.WriteByte('\\')
+		b.WriteByte(c)
+	}
+}
@@ -0,0 +1,312 @@
+// Package audit 提供命令执行的防篡改审计日志：每条记录通过哈希链与上一条记录绑定，
+// 篡改或删除任意一条历史记录都会导致后续链路的哈希校验失败，从而被 `deepsentry audit verify` 发现。
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// genesisHash 是链的起点，代表"没有前序记录"
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000000000000000"
+
+// Record 是单次命令执行的一条审计记录
+type Record struct {
+	Ts            time.Time `json:"ts"`
+	Host          string    `json:"host"`
+	User          string    `json:"user"`
+	Cwd           string    `json:"cwd"`
+	Cmd           string    `json:"cmd"`
+	Risk          string    `json:"risk"`
+	Reason        string    `json:"reason"`
+	ApprovedBy    string    `json:"approvedBy,omitempty"`
+	ExitCode      int       `json:"exitCode"`
+	StdoutSHA256  string    `json:"stdoutSHA256"`
+	PrevHash      string    `json:"prevHash"`
+	Hash          string    `json:"hash"`
+}
+
+// Logger 是一个按天滚动的哈希链审计日志写入器
+type Logger struct {
+	dir        string
+	passphrase string
+
+	mu       sync.Mutex
+	curDay   string
+	file     *os.File
+	prevHash string
+}
+
+// defaultLogger 是 Log 便捷函数使用的进程级单例，由 security.SafeExecV3 等调用方复用
+var (
+	defaultLogger   *Logger
+	defaultLoggerMu sync.Mutex
+)
+
+// dailyPath 按 YYYY-MM-DD 生成当天的日志文件路径
+func dailyPath(dir string, t time.Time) string {
+	return filepath.Join(dir, t.Format("2006-01-02")+".log")
+}
+
+// NewLogger 打开（或创建）dir 下的审计目录，并续接最近一条记录的哈希，保证跨天、跨进程重启链路不断
+// passphrase 非空时，每行记录会用 AES-CFB 加密后再落盘
+func NewLogger(dir, passphrase string) (*Logger, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建审计日志目录失败: %v", err)
+	}
+
+	l := &Logger{dir: dir, passphrase: passphrase, prevHash: genesisHash}
+
+	if last, err := lastRecord(dir, passphrase); err == nil && last != nil {
+		l.prevHash = last.Hash
+	}
+
+	if err := l.rotateIfNeeded(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Default 返回（并在首次调用时初始化）进程级默认 Logger，目录固定为 ~/.deepsentry/audit
+func Default() (*Logger, error) {
+	defaultLoggerMu.Lock()
+	defer defaultLoggerMu.Unlock()
+	if defaultLogger != nil {
+		return defaultLogger, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(home, ".deepsentry", "audit")
+	passphrase := os.Getenv("DEEPSENTRY_AUDIT_KEY")
+
+	l, err := NewLogger(dir, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defaultLogger = l
+	return l, nil
+}
+
+func (l *Logger) rotateIfNeeded() error {
+	day := time.Now().Format("2006-01-02")
+	if l.file != nil && l.curDay == day {
+		return nil
+	}
+	if l.file != nil {
+		l.file.Close()
+	}
+
+	f, err := os.OpenFile(dailyPath(l.dir, time.Now()), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("打开审计日志文件失败: %v", err)
+	}
+	l.file = f
+	l.curDay = day
+	return nil
+}
+
+// Append 写入一条记录，自动补全 Ts（为零值时）/PrevHash/Hash 字段，并按需做日切与加密；
+// Host/User/Cwd 等其余字段由调用方填写
+func (l *Logger) Append(rec Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.rotateIfNeeded(); err != nil {
+		return Record{}, err
+	}
+
+	if rec.Ts.IsZero() {
+		rec.Ts = time.Now()
+	}
+	rec.PrevHash = l.prevHash
+	rec.Hash = ""
+
+	canonical, err := canonicalize(rec)
+	if err != nil {
+		return Record{}, fmt.Errorf("记录序列化失败: %v", err)
+	}
+	sum := sha256.Sum256(append([]byte(rec.PrevHash), canonical...))
+	rec.Hash = hex.EncodeToString(sum[:])
+
+	// Hash 基于不含 hash 字段的 canonical 表示计算；写盘时再带上最终的 hash 字段
+	full, err := json.Marshal(rec)
+	if err != nil {
+		return Record{}, fmt.Errorf("记录序列化失败: %v", err)
+	}
+
+	line := string(full)
+	if l.passphrase != "" {
+		line, err = encryptLine(l.passphrase, line)
+		if err != nil {
+			return Record{}, fmt.Errorf("审计记录加密失败: %v", err)
+		}
+	}
+
+	if _, err := l.file.WriteString(line + "\n"); err != nil {
+		return Record{}, fmt.Errorf("写入审计日志失败: %v", err)
+	}
+
+	l.prevHash = rec.Hash
+	return rec, nil
+}
+
+// canonicalize 生成用于哈希计算的确定性表示：hash 字段清空后按固定字段顺序 Marshal
+// encoding/json 对 struct 按声明顺序序列化，天然确定，无需额外排序
+func canonicalize(rec Record) ([]byte, error) {
+	rec.Hash = ""
+	return json.Marshal(rec)
+}
+
+// Close 关闭底层文件句柄
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return l.file.Close()
+	}
+	return nil
+}
+
+// Log 是最常用的便捷入口：用默认 Logger 记录一条命令执行
+func Log(rec Record) {
+	l, err := Default()
+	if err != nil {
+		fmt.Printf("⚠️ [审计日志] 初始化失败，跳过记录: %v\n", err)
+		return
+	}
+	if _, err := l.Append(rec); err != nil {
+		fmt.Printf("⚠️ [审计日志] 写入失败: %v\n", err)
+	}
+}
+
+// listLogFiles 按文件名（即日期）升序列出 dir 下的全部审计日志文件
+func listLogFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".log") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readRecords 按顺序读取（并按需解密）一个日志文件里的全部记录
+func readRecords(path, passphrase string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		if passphrase != "" {
+			line, err = decryptLine(passphrase, line)
+			if err != nil {
+				return nil, fmt.Errorf("解密失败: %v", err)
+			}
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("解析记录失败: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// lastRecord 返回所有日志文件中时间上最后一条记录，用于 Logger 启动时续接哈希链
+func lastRecord(dir, passphrase string) (*Record, error) {
+	files, err := listLogFiles(dir)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		records, err := readRecords(files[i], passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			return &records[len(records)-1], nil
+		}
+	}
+	return nil, nil
+}
+
+// VerifyResult 是 Verify 的结果：链是否完整、第一处断裂的位置（文件+行号，从 1 开始计数）
+type VerifyResult struct {
+	OK          bool
+	BrokenFile  string
+	BrokenIndex int
+	Reason      string
+	TotalCount  int
+}
+
+// Verify 从头到尾走一遍哈希链，报告第一处被破坏的记录
+func Verify(dir, passphrase string) (VerifyResult, error) {
+	files, err := listLogFiles(dir)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+
+	prevHash := genesisHash
+	total := 0
+
+	for _, f := range files {
+		records, err := readRecords(f, passphrase)
+		if err != nil {
+			return VerifyResult{}, err
+		}
+		for i, rec := range records {
+			total++
+			if rec.PrevHash != prevHash {
+				return VerifyResult{
+					OK:          false,
+					BrokenFile:  f,
+					BrokenIndex: i + 1,
+					Reason:      fmt.Sprintf("prevHash 不匹配：期望 %s，记录中为 %s", prevHash, rec.PrevHash),
+					TotalCount:  total,
+				}, nil
+			}
+			canonical, err := canonicalize(rec)
+			if err != nil {
+				return VerifyResult{}, err
+			}
+			sum := sha256.Sum256(append([]byte(rec.PrevHash), canonical...))
+			expected := hex.EncodeToString(sum[:])
+			if rec.Hash != expected {
+				return VerifyResult{
+					OK:          false,
+					BrokenFile:  f,
+					BrokenIndex: i + 1,
+					Reason:      fmt.Sprintf("hash 不匹配：期望 %s，记录中为 %s（内容被篡改）", expected, rec.Hash),
+					TotalCount:  total,
+				}, nil
+			}
+			prevHash = rec.Hash
+		}
+	}
+
+	return VerifyResult{OK: true, TotalCount: total}, nil
+}
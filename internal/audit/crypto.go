@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// deriveKey 由口令派生出 AES-256 密钥
+// 审计日志单行独立加密（各自带随机 IV），不需要像配置密钥那样做慢哈希抗爆破，sha256 足够
+func deriveKey(passphrase string) []byte {
+	sum := sha256.Sum256([]byte(passphrase))
+	return sum[:]
+}
+
+// encryptLine 用 AES-CFB 加密一行 JSON，输出 hex(iv || ciphertext)
+// 每行独立生成随机 IV，即便口令相同也不会暴露明文间的关系
+func encryptLine(passphrase, plaintext string) (string, error) {
+	block, err := aes.NewCipher(deriveKey(passphrase))
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext, []byte(plaintext))
+
+	return hex.EncodeToString(iv) + hex.EncodeToString(ciphertext), nil
+}
+
+// decryptLine 是 encryptLine 的逆操作
+func decryptLine(passphrase, line string) (string, error) {
+	raw, err := hex.DecodeString(line)
+	if err != nil {
+		return "", fmt.Errorf("无效的加密行: %v", err)
+	}
+	if len(raw) < aes.BlockSize {
+		return "", fmt.Errorf("加密行过短")
+	}
+
+	block, err := aes.NewCipher(deriveKey(passphrase))
+	if err != nil {
+		return "", err
+	}
+
+	iv, ciphertext := raw[:aes.BlockSize], raw[aes.BlockSize:]
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(plaintext, ciphertext)
+
+	return string(plaintext), nil
+}
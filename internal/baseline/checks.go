@@ -0,0 +1,96 @@
+// Package baseline 实现基线合规扫描：检查项由 checks.yaml 声明式定义，
+// 扫描在当前激活的 executor（Local/SSH/Fanout）上执行，产出 JSON/xlsx 报告，
+// 设计上镜像常见的 ssh-baseline 工具，但复用 DeepSentry 已有的 executor 连接管理。
+package baseline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Check 是 checks.yaml 里声明的一条基线检查项
+type Check struct {
+	ID          string `yaml:"id"`
+	Description string `yaml:"description"`
+	// Platform 取 "linux"/"windows"，留空表示两个平台都适用
+	Platform    string `yaml:"platform"`
+	Cmd         string `yaml:"cmd"`
+	Expect      Expect `yaml:"expect"`
+	Remediation string `yaml:"remediation"`
+	// Weight 计分权重，缺省按 1 处理
+	Weight int `yaml:"weight"`
+}
+
+// Expect 描述一条检查项命令输出的期望规则，同一时刻只应填写其中一种判定方式
+type Expect struct {
+	Regex    string `yaml:"regex"`
+	Contains string `yaml:"contains"`
+	Equals   string `yaml:"equals"`
+
+	// JSONPath/JSONValue: 把输出当 JSON 解析，按点号路径（支持 a.b[0] 形式）取值后与 JSONValue 比较
+	JSONPath  string `yaml:"json_path"`
+	JSONValue string `yaml:"json_value"`
+
+	// NumericOp/NumericValue: 把输出解析为 float64，按 NumericOp (">" ">=" "<" "<=" "==") 与 NumericValue 比较
+	NumericOp    string  `yaml:"numeric_op"`
+	NumericValue float64 `yaml:"numeric_value"`
+}
+
+// checksFile 对应 checks.yaml 的顶层结构
+type checksFile struct {
+	Checks []Check `yaml:"checks"`
+}
+
+// LoadChecks 从指定路径读取并解析 checks.yaml
+func LoadChecks(path string) ([]Check, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 checks.yaml 失败: %v", err)
+	}
+
+	var cf checksFile
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		return nil, fmt.Errorf("解析 checks.yaml 失败: %v", err)
+	}
+
+	for i := range cf.Checks {
+		if cf.Checks[i].Weight <= 0 {
+			cf.Checks[i].Weight = 1
+		}
+	}
+	return cf.Checks, nil
+}
+
+// ResolveChecksPath 解析 checks.yaml 的实际路径：
+// --checks 显式路径优先；否则按 --profile 在 ./checks/<profile>.yaml、~/.deepsentry/checks/<profile>.yaml、
+// /etc/deepsentry/checks/<profile>.yaml 中查找，最后回退到不带 profile 的 checks.yaml 同名搜索路径
+func ResolveChecksPath(explicit, profile string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+
+	var candidates []string
+	if profile != "" {
+		candidates = append(candidates, filepath.Join("checks", profile+".yaml"))
+		if home, err := os.UserHomeDir(); err == nil {
+			candidates = append(candidates, filepath.Join(home, ".deepsentry", "checks", profile+".yaml"))
+		}
+		candidates = append(candidates, filepath.Join("/etc/deepsentry/checks", profile+".yaml"))
+	}
+
+	candidates = append(candidates, "checks.yaml")
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".deepsentry", "checks.yaml"))
+	}
+	candidates = append(candidates, "/etc/deepsentry/checks.yaml")
+
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("未找到 checks.yaml (profile=%q)，请通过 --checks 指定路径", profile)
+}
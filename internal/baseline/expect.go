@@ -0,0 +1,118 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Evaluate 判定 output 是否满足 Expect 规则，返回是否通过及未通过时的原因
+func (e Expect) Evaluate(output string) (bool, string) {
+	switch {
+	case e.Regex != "":
+		re, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return false, fmt.Sprintf("无效的 regex %q: %v", e.Regex, err)
+		}
+		if re.MatchString(output) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("输出未匹配正则 %q", e.Regex)
+
+	case e.Contains != "":
+		if strings.Contains(output, e.Contains) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("输出未包含 %q", e.Contains)
+
+	case e.Equals != "":
+		if strings.TrimSpace(output) == strings.TrimSpace(e.Equals) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("输出与期望值 %q 不一致", e.Equals)
+
+	case e.JSONPath != "":
+		val, err := extractJSONPath(output, e.JSONPath)
+		if err != nil {
+			return false, fmt.Sprintf("json_path 提取失败: %v", err)
+		}
+		if fmt.Sprintf("%v", val) == e.JSONValue {
+			return true, ""
+		}
+		return false, fmt.Sprintf("json_path %s 取值 %v 与期望值 %q 不一致", e.JSONPath, val, e.JSONValue)
+
+	case e.NumericOp != "":
+		n, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if err != nil {
+			return false, fmt.Sprintf("输出无法解析为数值: %v", err)
+		}
+		if compareNumeric(n, e.NumericOp, e.NumericValue) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("数值 %v 不满足 %s %v", n, e.NumericOp, e.NumericValue)
+
+	default:
+		return false, "expect 规则为空，无法判定"
+	}
+}
+
+func compareNumeric(n float64, op string, v float64) bool {
+	switch op {
+	case ">":
+		return n > v
+	case ">=":
+		return n >= v
+	case "<":
+		return n < v
+	case "<=":
+		return n <= v
+	case "==":
+		return n == v
+	default:
+		return false
+	}
+}
+
+// extractJSONPath 从 raw 解析出的 JSON 里按点号路径取值，路径段支持 "key[index]" 数组下标写法
+func extractJSONPath(raw, path string) (interface{}, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return nil, err
+	}
+
+	cur := data
+	for _, seg := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		key := seg
+		idx := -1
+		if open := strings.Index(seg, "["); open != -1 && strings.HasSuffix(seg, "]") {
+			key = seg[:open]
+			n, err := strconv.Atoi(seg[open+1 : len(seg)-1])
+			if err != nil {
+				return nil, fmt.Errorf("无效的数组下标: %s", seg)
+			}
+			idx = n
+		}
+
+		if key != "" {
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("路径 %s 处不是对象", key)
+			}
+			v, ok := m[key]
+			if !ok {
+				return nil, fmt.Errorf("路径中不存在键 %s", key)
+			}
+			cur = v
+		}
+		if idx >= 0 {
+			arr, ok := cur.([]interface{})
+			if !ok || idx >= len(arr) {
+				return nil, fmt.Errorf("路径 %s 处不是有效数组", seg)
+			}
+			cur = arr[idx]
+		}
+	}
+	return cur, nil
+}
@@ -0,0 +1,86 @@
+package baseline
+
+import (
+	"ai-edr/internal/config"
+	"ai-edr/internal/executor"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// defaultFleetConcurrency 同时进行基线扫描的主机数上限
+const defaultFleetConcurrency = 10
+
+// RunAll 对当前激活的执行器跑一遍 checks：
+//   - LocalExecutor / SSHExecutor（单主机）：返回长度为 1 的报告列表
+//   - FanoutExecutor（多主机）：对扇出配置里的每台主机各自建立连接，并发产出一份报告，
+//     一条命令即可完成整支机群的基线扫描
+func RunAll(checks []Check) ([]Report, error) {
+	if executor.Current == nil {
+		return nil, fmt.Errorf("执行器未初始化")
+	}
+
+	if fe, ok := executor.Current.(*executor.FanoutExecutor); ok {
+		return runFleet(fe, checks), nil
+	}
+
+	report, err := RunHost(executor.Current, currentHostLabel(), checks)
+	if err != nil {
+		return nil, err
+	}
+	return []Report{report}, nil
+}
+
+// runFleet 并发地在扇出配置的每台主机上各自跑一遍 checks
+func runFleet(fe *executor.FanoutExecutor, checks []Check) []Report {
+	hosts := fe.Hosts()
+	reports := make([]Report, len(hosts))
+
+	sem := make(chan struct{}, defaultFleetConcurrency)
+	var wg sync.WaitGroup
+
+	for i, h := range hosts {
+		i, h := i, h
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			label := hostLabel(h)
+			exe, err := fe.HostExecutor(h)
+			if err != nil {
+				reports[i] = Report{Host: label, Err: err.Error()}
+				return
+			}
+
+			report, err := RunHost(exe, label, checks)
+			if err != nil {
+				reports[i] = Report{Host: label, Err: err.Error()}
+				return
+			}
+			reports[i] = report
+		}()
+	}
+	wg.Wait()
+	return reports
+}
+
+// hostLabel 为 HostSpec 生成与 FanoutExecutor 内部一致风格的标识
+func hostLabel(h config.HostSpec) string {
+	if h.User != "" {
+		return fmt.Sprintf("%s@%s", h.User, h.Host)
+	}
+	return h.Host
+}
+
+// currentHostLabel 返回单主机模式下用于报告的主机标识
+func currentHostLabel() string {
+	if config.GlobalConfig.SSHHost != "" {
+		return config.GlobalConfig.SSHHost
+	}
+	if h, err := os.Hostname(); err == nil {
+		return h
+	}
+	return "unknown"
+}
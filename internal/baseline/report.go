@@ -0,0 +1,90 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// WriteJSON 把扫描结果写成 JSON 文件
+func WriteJSON(reports []Report, path string) error {
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化报告失败: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入 JSON 报告失败: %v", err)
+	}
+	return nil
+}
+
+// xlsxHeaders 是每个 sheet 的列标题，每列对应 CheckResult 的一个字段（Err 不单独成列）
+var xlsxHeaders = []string{"ID", "Description", "Pass", "Weight", "Output", "Reason", "Remediation"}
+
+// WriteXLSX 把扫描结果写成一张 xlsx 表格，每台主机对应一个 sheet
+func WriteXLSX(reports []Report, path string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, report := range reports {
+		sheet := report.Host
+		if sheet == "" {
+			sheet = fmt.Sprintf("host-%d", i+1)
+		}
+
+		if i == 0 {
+			f.SetSheetName("Sheet1", sheet)
+		} else if _, err := f.NewSheet(sheet); err != nil {
+			return fmt.Errorf("创建 sheet %s 失败: %v", sheet, err)
+		}
+
+		if err := writeSheetHeader(f, sheet); err != nil {
+			return err
+		}
+		if err := writeSheetRows(f, sheet, report); err != nil {
+			return err
+		}
+	}
+
+	if err := f.SaveAs(path); err != nil {
+		return fmt.Errorf("写入 xlsx 报告失败: %v", err)
+	}
+	return nil
+}
+
+func writeSheetHeader(f *excelize.File, sheet string) error {
+	for col, h := range xlsxHeaders {
+		cell, err := excelize.CoordinatesToCellName(col+1, 1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellValue(sheet, cell, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSheetRows(f *excelize.File, sheet string, report Report) error {
+	if report.Err != "" {
+		cell, _ := excelize.CoordinatesToCellName(1, 2)
+		return f.SetCellValue(sheet, cell, "扫描失败: "+report.Err)
+	}
+
+	for row, res := range report.Results {
+		r := row + 2
+		values := []interface{}{res.ID, res.Description, res.Pass, res.Weight, res.Output, res.Reason, res.Remediation}
+		for col, v := range values {
+			cell, err := excelize.CoordinatesToCellName(col+1, r)
+			if err != nil {
+				return err
+			}
+			if err := f.SetCellValue(sheet, cell, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package baseline
+
+import (
+	"ai-edr/internal/executor"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCheckConcurrency 单台主机上并发执行的 check 数上限
+const defaultCheckConcurrency = 8
+
+// CheckResult 是单条 check 在某台主机上的执行结果
+type CheckResult struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Pass        bool   `json:"pass"`
+	Output      string `json:"output"`
+	Reason      string `json:"reason,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+	Weight      int    `json:"weight"`
+	Err         string `json:"err,omitempty"`
+}
+
+// Report 是某台主机的完整基线扫描结果
+type Report struct {
+	Host        string        `json:"host"`
+	GeneratedAt time.Time     `json:"generatedAt"`
+	Results     []CheckResult `json:"results,omitempty"`
+	Score       int           `json:"score"`
+	MaxScore    int           `json:"maxScore"`
+	// Err 非空表示该主机整体扫描失败（如连接失败），此时 Results 为空
+	Err string `json:"err,omitempty"`
+}
+
+// RunHost 在 exe 对应的单台主机上并发跑完 checks（先按 Platform 过滤），按声明顺序返回结果
+func RunHost(exe executor.Executor, host string, checks []Check) (Report, error) {
+	applicable := filterByPlatform(checks, detectPlatform(exe))
+
+	results := make([]CheckResult, len(applicable))
+	sem := make(chan struct{}, defaultCheckConcurrency)
+	var wg sync.WaitGroup
+
+	for i, c := range applicable {
+		i, c := i, c
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = runCheck(exe, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Host: host, GeneratedAt: time.Now(), Results: results}
+	for _, r := range results {
+		report.MaxScore += r.Weight
+		if r.Pass {
+			report.Score += r.Weight
+		}
+	}
+	return report, nil
+}
+
+// runCheck 执行单条 check 并对比输出与 Expect 规则
+func runCheck(exe executor.Executor, c Check) CheckResult {
+	res := CheckResult{ID: c.ID, Description: c.Description, Remediation: c.Remediation, Weight: c.Weight}
+
+	out, err := exe.Run(c.Cmd)
+	res.Output = out
+	if err != nil {
+		res.Err = err.Error()
+		res.Reason = fmt.Sprintf("命令执行失败: %v", err)
+		return res
+	}
+
+	res.Pass, res.Reason = c.Expect.Evaluate(out)
+	return res
+}
+
+// filterByPlatform 只保留 Platform 留空或与目标平台匹配的 check
+func filterByPlatform(checks []Check, platform string) []Check {
+	var out []Check
+	for _, c := range checks {
+		if c.Platform == "" || strings.EqualFold(c.Platform, platform) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// detectPlatform 猜测目标执行器所在操作系统（"linux"/"windows"）
+// 本地模式直接取 runtime.GOOS；远程模式虽然会发一条 uname -s 探测命令，但无论探测成功、失败
+// 还是输出里不含 linux，都返回 linux（CIS 场景以 Linux 为主），远程主机不会被识别为 windows
+func detectPlatform(exe executor.Executor) string {
+	if !exe.IsRemote() {
+		if runtime.GOOS == "windows" {
+			return "windows"
+		}
+		return "linux"
+	}
+	if out, err := exe.Run("uname -s"); err == nil && strings.Contains(strings.ToLower(out), "linux") {
+		return "linux"
+	}
+	return "linux"
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -19,11 +20,48 @@ type Config struct {
 	ApiKey      string  `mapstructure:"api_key"`
 	Temperature float64 `mapstructure:"temperature"`
 
+	// --- 多 Provider 配置 ---
+	// Providers 按顺序声明可用的 LLM 后端；非空时优先于上面那组扁平的 ApiURL/ApiKey/ModelName 字段，
+	// 使得 primary 掉线（429/5xx/超时）时能自动切到 fallback，compressHistory 也能单独指定一个
+	// 更便宜的 summarizer（比如本地 Ollama 模型）
+	Providers []ProviderSpec `mapstructure:"providers"`
+
 	// --- SSH 配置 ---
 	SSHHost     string `mapstructure:"ssh_host"`
 	SSHUser     string `mapstructure:"ssh_user"`
 	SSHPassword string `mapstructure:"ssh_password"`
 	SSHKeyPath  string `mapstructure:"ssh_key_path"`
+
+	// SSHKnownHostsPath 管理的 known_hosts 存储路径
+	SSHKnownHostsPath string `mapstructure:"ssh_known_hosts_path"`
+	// StrictHostKeyChecking 主机密钥校验策略: strict|ask|accept-new|off
+	StrictHostKeyChecking string `mapstructure:"strict_host_key_checking"`
+
+	// --- 多主机扇出配置 ---
+	// SSHHosts 定义了 Fanout 模式下的主机清单；当其非空时，Init 会优先构建 FanoutExecutor
+	SSHHosts []HostSpec `mapstructure:"ssh_hosts"`
+}
+
+// HostSpec 描述扇出执行器中的单台目标主机
+type HostSpec struct {
+	Host     string   `mapstructure:"host"`
+	User     string   `mapstructure:"user"`
+	Password string   `mapstructure:"password"`
+	KeyPath  string   `mapstructure:"key_path"`
+	Port     int      `mapstructure:"port"`
+	Tags     []string `mapstructure:"tags"`
+}
+
+// ProviderSpec 描述 providers 列表里的单个 LLM 后端
+type ProviderSpec struct {
+	// Name 决定走哪个具体实现: openai | anthropic | gemini | ollama，留空按 openai 处理
+	Name string `mapstructure:"name"`
+	// Role 决定这个 provider 在 Router 里的位置: primary（默认）| fallback | summarizer
+	Role        string  `mapstructure:"role"`
+	ApiURL      string  `mapstructure:"api_url"`
+	ApiKey      string  `mapstructure:"api_key"`
+	ModelName   string  `mapstructure:"model_name"`
+	Temperature float64 `mapstructure:"temperature"`
 }
 
 // InitConfig 初始化配置 (核心加载逻辑)
@@ -55,6 +93,10 @@ func InitConfig(cfgFile string) error {
 	viper.SetDefault("model_name", "deepseek-chat")
 	viper.SetDefault("temperature", 0.0)
 	viper.SetDefault("ssh_user", "root")
+	viper.SetDefault("strict_host_key_checking", "strict")
+	if home, err := os.UserHomeDir(); err == nil {
+		viper.SetDefault("ssh_known_hosts_path", filepath.Join(home, ".deepsentry", "known_hosts"))
+	}
 
 	// 4. 开启环境变量自动覆盖
 	// 例如: export DEEPSENTRY_API_KEY="xxx" 会自动覆盖配置文件中的 api_key
@@ -77,13 +119,80 @@ func InitConfig(cfgFile string) error {
 		return fmt.Errorf("配置解析失败: %w", err)
 	}
 
+	// 7. 敏感字段解密：api_key/ssh_password 若以 "enc:" 前缀写入，则在此还原为明文
+	// 解密后的明文只存在于内存中，SaveConfig 写回磁盘时会重新加密
+	if err := decryptSecrets(&GlobalConfig); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // SaveConfig 将当前 Viper 中的配置保存到文件 (默认保存到当前目录)
+// SensitiveFields 列出的扁平字段、SensitiveListFields 列出的列表子字段都会在写盘前自动加密，
+// 内存中的 viper 状态随后立即还原为明文
 func SaveConfig() error {
 	// 确保默认保存为 yaml 格式
 	viper.SetConfigType("yaml")
+
+	plaintext := make(map[string]string, len(SensitiveFields))
+	for _, field := range SensitiveFields {
+		value := viper.GetString(field)
+		if value == "" || strings.HasPrefix(value, encPrefix) {
+			continue
+		}
+		sealed, err := SealField(value)
+		if err != nil {
+			return fmt.Errorf("加密字段 %s 失败: %v", field, err)
+		}
+		plaintext[field] = value
+		viper.Set(field, sealed)
+	}
+	defer func() {
+		for field, value := range plaintext {
+			viper.Set(field, value)
+		}
+	}()
+
+	// ssh_hosts[].password / providers[].api_key 是列表，viper.GetString 够不到元素里的子字段，
+	// 这里整个顶层 key 一起读出来、改完子字段再整个写回去，并在 defer 里整体还原成明文
+	originalLists := make(map[string]interface{}, len(SensitiveListFields))
+	for _, lf := range SensitiveListFields {
+		raw := viper.Get(lf.Key)
+		items, ok := raw.([]interface{})
+		if !ok || len(items) == 0 {
+			continue
+		}
+		originalLists[lf.Key] = raw
+
+		sealedItems := make([]interface{}, len(items))
+		for i, it := range items {
+			m, ok := it.(map[string]interface{})
+			if !ok {
+				sealedItems[i] = it
+				continue
+			}
+			sealedCopy := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				sealedCopy[k] = v
+			}
+			if v, ok := m[lf.SubField].(string); ok && v != "" && !strings.HasPrefix(v, encPrefix) {
+				sealed, err := SealField(v)
+				if err != nil {
+					return fmt.Errorf("加密字段 %s[%d].%s 失败: %v", lf.Key, i, lf.SubField, err)
+				}
+				sealedCopy[lf.SubField] = sealed
+			}
+			sealedItems[i] = sealedCopy
+		}
+		viper.Set(lf.Key, sealedItems)
+	}
+	defer func() {
+		for key, orig := range originalLists {
+			viper.Set(key, orig)
+		}
+	}()
+
 	// 保存到当前目录下的 config.yaml
 	return viper.WriteConfigAs("config.yaml")
 }
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// encPrefix 标记一个配置字段值是密文，而非明文
+const encPrefix = "enc:"
+
+// IsSealed 判断 value 是否已经是 SealField 产出的密文，供需要避免重复加密的调用方使用
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, encPrefix)
+}
+
+// keyringService / keyringUser 是 go-keyring 里用来定位主密钥的键
+const (
+	keyringService = "deepsentry"
+	keyringUser    = "master-key"
+)
+
+// SensitiveFields 列出写回磁盘前需要自动加密的扁平字段（viper/mapstructure 的 key 名）
+var SensitiveFields = []string{"api_key", "ssh_password"}
+
+// SensitiveListFields 列出 ssh_hosts/providers 这类列表字段里，每个元素需要在写盘前加密的子字段。
+// chunk0-1 的 ssh_hosts 与 chunk1-5 的 providers 都各自带了一份独立的密码/api_key，
+// 不在这里登记的话就只有最早的两个扁平字段 (api_key/ssh_password) 会被 SaveConfig 加密
+var SensitiveListFields = []struct {
+	Key      string // 顶层 viper key
+	SubField string // 列表里每个元素需要加密的子字段
+}{
+	{Key: "ssh_hosts", SubField: "password"},
+	{Key: "providers", SubField: "api_key"},
+}
+
+// rawSecrets 保存解密后的敏感字段原始字节，供 Close() 做尽力而为的内存清零
+// Go 的 string 不可变、GC 也不保证立即回收旧副本，这里只能做到"尽力清零"，不是密码学级别的保证
+var rawSecrets [][]byte
+
+// saltPath 是派生主密钥用的 scrypt 盐的存放位置
+func saltPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".deepsentry", "secret.salt"), nil
+}
+
+// loadOrCreateSalt 读取既有盐，不存在则生成一份新的并持久化
+func loadOrCreateSalt() ([]byte, error) {
+	path, err := saltPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		return data, nil
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, salt, 0600); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// resolveMasterKey 按优先级解析用于加解密的主密钥：
+// 1. DEEPSENTRY_MASTER_KEY 环境变量
+// 2. OS 密钥链中名为 deepsentry 的条目
+// 3. 启动时交互式提示输入口令
+// 拿到口令后用 scrypt 派生出定长密钥，从不把口令本身落盘
+func resolveMasterKey() ([]byte, error) {
+	var passphrase string
+
+	if v := os.Getenv("DEEPSENTRY_MASTER_KEY"); v != "" {
+		passphrase = v
+	} else if v, err := keyring.Get(keyringService, keyringUser); err == nil && v != "" {
+		passphrase = v
+	} else {
+		fmt.Print("🔐 请输入主密钥口令以解密配置中的敏感字段: ")
+		bytePass, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return nil, fmt.Errorf("读取口令失败: %v", err)
+		}
+		passphrase = string(bytePass)
+	}
+
+	salt, err := loadOrCreateSalt()
+	if err != nil {
+		return nil, fmt.Errorf("加载加密盐失败: %v", err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("密钥派生失败: %v", err)
+	}
+	rawSecrets = append(rawSecrets, []byte(passphrase))
+	return key, nil
+}
+
+// encryptValue 用 AES-CFB 加密明文，返回 "enc:<base64(iv||ciphertext)>"
+func encryptValue(key []byte, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext, []byte(plaintext))
+
+	return encPrefix + base64.StdEncoding.EncodeToString(append(iv, ciphertext...)), nil
+}
+
+// decryptValue 是 encryptValue 的逆操作；传入非 "enc:" 前缀的值时原样返回（视为明文）
+func decryptValue(key []byte, value string) (string, error) {
+	if !strings.HasPrefix(value, encPrefix) {
+		return value, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encPrefix))
+	if err != nil {
+		return "", fmt.Errorf("无效的密文编码: %v", err)
+	}
+	if len(raw) < aes.BlockSize {
+		return "", fmt.Errorf("密文过短")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	iv, ciphertext := raw[:aes.BlockSize], raw[aes.BlockSize:]
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(plaintext, ciphertext)
+
+	rawSecrets = append(rawSecrets, plaintext)
+	return string(plaintext), nil
+}
+
+// decryptSecrets 把 cfg 里以 "enc:" 前缀标记的敏感字段原地解密，覆盖扁平字段
+// (api_key/ssh_password) 以及 ssh_hosts[].password / providers[].api_key 这两个列表字段。
+// 只要没有任何字段带 "enc:" 前缀就完全跳过 resolveMasterKey，避免无谓地索要口令
+func decryptSecrets(cfg *Config) error {
+	needsKey := strings.HasPrefix(cfg.ApiKey, encPrefix) || strings.HasPrefix(cfg.SSHPassword, encPrefix)
+	for _, h := range cfg.SSHHosts {
+		if strings.HasPrefix(h.Password, encPrefix) {
+			needsKey = true
+			break
+		}
+	}
+	for _, p := range cfg.Providers {
+		if strings.HasPrefix(p.ApiKey, encPrefix) {
+			needsKey = true
+			break
+		}
+	}
+	if !needsKey {
+		return nil
+	}
+
+	key, err := resolveMasterKey()
+	if err != nil {
+		return fmt.Errorf("解密配置密钥失败: %v", err)
+	}
+
+	if plain, err := decryptValue(key, cfg.ApiKey); err != nil {
+		return fmt.Errorf("解密 api_key 失败: %v", err)
+	} else {
+		cfg.ApiKey = plain
+	}
+
+	if plain, err := decryptValue(key, cfg.SSHPassword); err != nil {
+		return fmt.Errorf("解密 ssh_password 失败: %v", err)
+	} else {
+		cfg.SSHPassword = plain
+	}
+
+	for i := range cfg.SSHHosts {
+		plain, err := decryptValue(key, cfg.SSHHosts[i].Password)
+		if err != nil {
+			return fmt.Errorf("解密 ssh_hosts[%d].password 失败: %v", i, err)
+		}
+		cfg.SSHHosts[i].Password = plain
+	}
+
+	for i := range cfg.Providers {
+		plain, err := decryptValue(key, cfg.Providers[i].ApiKey)
+		if err != nil {
+			return fmt.Errorf("解密 providers[%d].api_key 失败: %v", i, err)
+		}
+		cfg.Providers[i].ApiKey = plain
+	}
+
+	return nil
+}
+
+// CloseSecrets 尽力而为地清零本进程持有过的解密后敏感字节，并清空内存中的明文字段
+// 注意：Go 的 string 不可变，旧副本何时被 GC 回收不可控，这里不是密码学级别的保证
+func CloseSecrets() {
+	for _, b := range rawSecrets {
+		for i := range b {
+			b[i] = 0
+		}
+	}
+	rawSecrets = nil
+	GlobalConfig.ApiKey = ""
+	GlobalConfig.SSHPassword = ""
+	for i := range GlobalConfig.SSHHosts {
+		GlobalConfig.SSHHosts[i].Password = ""
+	}
+	for i := range GlobalConfig.Providers {
+		GlobalConfig.Providers[i].ApiKey = ""
+	}
+}
+
+// SealField 返回 value 的加密形式，供 `deepsentry config seal` 与 SaveConfig 共用
+func SealField(value string) (string, error) {
+	key, err := resolveMasterKey()
+	if err != nil {
+		return "", err
+	}
+	return encryptValue(key, value)
+}
+
+// UnsealField 返回 value 的明文形式（若本就是明文则原样返回）
+func UnsealField(value string) (string, error) {
+	if !strings.HasPrefix(value, encPrefix) {
+		return value, nil
+	}
+	key, err := resolveMasterKey()
+	if err != nil {
+		return "", err
+	}
+	return decryptValue(key, value)
+}
@@ -0,0 +1,22 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultSocketAddr 算出 "daemon run" 未显式传 --addr 时使用的默认监听地址。
+// 优先落在 $XDG_RUNTIME_DIR（系统登录会话私有、重启即清空，大多数发行版默认 0700），
+// 取不到时退回 ~/.deepsentry——都不是 /tmp 这种所有本地用户可写的公共目录，
+// 避免其它用户能抢先在同名路径创建 socket 或探测到它的存在。连 home 目录都拿不到时，
+// 最后才落到 os.TempDir()
+func DefaultSocketAddr() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return "unix://" + filepath.Join(dir, "deepsentryd.sock")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return "unix://" + filepath.Join(home, ".deepsentry", "deepsentryd.sock")
+	}
+	return fmt.Sprintf("unix://%s", filepath.Join(os.TempDir(), "deepsentryd.sock"))
+}
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tokenEnvVar 允许运维直接指定 token（例如由编排系统注入），优先于磁盘上的 token 文件
+const tokenEnvVar = "DEEPSENTRY_DAEMON_TOKEN"
+
+// tokenPath 是自动生成的共享密钥 token 的落盘位置，权限 0600，仅当前用户可读
+func tokenPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".deepsentry", "daemon.token"), nil
+}
+
+// loadOrCreateToken 读取既有 token，不存在则生成一份新的 32 字节随机 token 并持久化。
+// 这是 deepsentryd 在接入真正的用户认证之前的最低限度防护：本地其它用户若连不上这个
+// token 文件，就拿不到 HTTP API 的访问权限
+func loadOrCreateToken() (string, error) {
+	if v := os.Getenv(tokenEnvVar); v != "" {
+		return v, nil
+	}
+
+	path, err := tokenPath()
+	if err != nil {
+		return "", err
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// requireToken 用一个校验 Authorization: Bearer <token> 头的中间件包住 next；
+// 没带或带错 token 的请求一律 401，不透出任何会话/审批数据
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, fmt.Sprintf("未授权：请在 Authorization 头带上 deepsentryd 的 token（见 %s 或环境变量 %s）", mustTokenPathForError(), tokenEnvVar), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// mustTokenPathForError 仅用于 401 响应里提示 token 文件路径；拿不到 home 目录时退化为占位提示
+func mustTokenPathForError() string {
+	path, err := tokenPath()
+	if err != nil {
+		return "~/.deepsentry/daemon.token"
+	}
+	return path
+}
+
+// TokenPathHint 供 "daemon run" 启动时打印提示，告诉操作者 token 落在哪个文件
+func TokenPathHint() string {
+	return mustTokenPathForError()
+}
@@ -0,0 +1,61 @@
+// Package daemon 实现 deepsentryd：一个常驻进程，按 dockerd/docker 的 C/S 模式
+// 把 analyzer + collector + security 的 Agent 循环搬到服务端，deepsentry 本体
+// 退化为一个转发用户输入/审批决定的瘦客户端。这样 LLM 会话的 history 和 api_key
+// 都只存在于 deepsentryd 进程里，终端崩溃、重连，或多名分析师同时盯着同一台
+// 受控主机，都不会丢失或打断会话
+package daemon
+
+import (
+	"ai-edr/internal/analyzer"
+	"ai-edr/internal/analyzer/recorder"
+	"ai-edr/internal/config"
+	"fmt"
+	"net/http"
+)
+
+// DaemonCli 类比 Docker 的 DaemonCli：拥有 HTTP API Server、全局配置与会话状态，
+// 是 deepsentryd 进程的顶层入口。这个仓库里只有一个可执行文件，没有独立的
+// deepsentryd 二进制，所以 DaemonCli 在这里落地为 "daemon run" 子命令背后的对象，
+// 而不是单独的 main 包
+type DaemonCli struct {
+	cfg        config.Config
+	server     *Server
+	recordPath string // 非空时，Serve 期间把每一步 Agent 交互录制到这个文件，供之后 replay
+}
+
+// NewDaemonCli 用当前已加载的全局配置构造一个 DaemonCli
+func NewDaemonCli(cfg config.Config) *DaemonCli {
+	return &DaemonCli{cfg: cfg, server: NewServer()}
+}
+
+// SetRecordPath 启用（传空字符串则关闭）HAR 风格录制，由 "daemon run --record" 驱动
+func (d *DaemonCli) SetRecordPath(path string) { d.recordPath = path }
+
+// Serve 在 addr 上阻塞式地提供 HTTP API：
+//   - addr 以 "unix://" 开头时监听 Unix Domain Socket（Linux/Mac 推荐，不暴露到网络）
+//   - 否则按 "host:port" 监听普通 TCP
+func (d *DaemonCli) Serve(addr string) error {
+	if d.recordPath != "" {
+		rec, err := recorder.New(d.recordPath)
+		if err != nil {
+			return fmt.Errorf("启用录制失败: %v", err)
+		}
+		defer rec.Close()
+		analyzer.SetRecorder(rec)
+		defer analyzer.SetRecorder(nil)
+	}
+
+	ln, err := listen(addr)
+	if err != nil {
+		return fmt.Errorf("deepsentryd 监听失败: %v", err)
+	}
+	defer ln.Close()
+
+	token, err := loadOrCreateToken()
+	if err != nil {
+		return fmt.Errorf("初始化 API token 失败: %v", err)
+	}
+
+	httpServer := &http.Server{Handler: requireToken(token, d.server.Routes())}
+	return httpServer.Serve(ln)
+}
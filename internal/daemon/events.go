@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+// Event 是通过 SSE 推送给订阅者的一条会话事件
+type Event struct {
+	Type      string      `json:"type"` // thought | awaiting_approval | output | final | error | denied
+	SessionID string      `json:"session_id"`
+	Ts        time.Time   `json:"ts"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// eventBus 是单个 Session 内部的广播器：一个会话可以同时被多个客户端订阅
+// (Web UI + 一个远程响应者都在看同一路事件)，任一方慢消费只会丢自己的事件，不阻塞主循环
+type eventBus struct {
+	mu   sync.Mutex
+	subs map[int]chan Event
+	next int
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[int]chan Event)}
+}
+
+func (b *eventBus) subscribe() (int, chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	ch := make(chan Event, 32)
+	b.subs[id] = ch
+	return id, ch
+}
+
+func (b *eventBus) unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ch, ok := b.subs[id]; ok {
+		delete(b.subs, id)
+		close(ch)
+	}
+}
+
+// publish 把事件广播给所有当前订阅者；订阅者的 channel 已满时直接丢弃，不阻塞 Agent 循环
+func (b *eventBus) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
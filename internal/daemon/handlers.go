@@ -0,0 +1,186 @@
+package daemon
+
+import (
+	"ai-edr/internal/collector"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Routes 组装 deepsentryd 对外暴露的全部 HTTP 接口
+func (srv *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/agent/sessions", srv.handleCreateSession)
+	mux.HandleFunc("/v1/agent/step", srv.handleStep)
+	mux.HandleFunc("/v1/agent/sessions/", srv.handleSessionSubroute) // .../{id}/events
+	mux.HandleFunc("/v1/commands/", srv.handleApproveSubroute)       // .../{id}/approve
+	mux.HandleFunc("/v1/context", srv.handleContext)
+	return mux
+}
+
+type createSessionRequest struct {
+	Context collector.SystemContext `json:"context"`
+}
+
+// handleCreateSession 创建一个新会话：客户端（TUI/Web UI）一次性提交本机采集到的 SystemContext，
+// 之后的 step 调用只需要带 session_id
+func (srv *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req createSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("请求体解析失败: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s := srv.createSession(req.Context)
+	writeJSON(w, http.StatusCreated, map[string]string{"session_id": s.ID})
+}
+
+type stepRequest struct {
+	SessionID string `json:"session_id"`
+	Input     string `json:"input"`
+}
+
+// handleStep 推进一个会话的 Agent 循环：如果带了 input，先把它追加为一条用户消息。
+// 循环本身异步运行，进度通过 SSE 事件流可见；这里立即返回 202，不等待完成
+func (srv *Server) handleStep(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req stepRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("请求体解析失败: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s, err := srv.session(req.SessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.appendUserInput(req.Input)
+	go s.step(context.Background())
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "running"})
+}
+
+// handleSessionSubroute 分发 /v1/agent/sessions/{id}/events
+func (srv *Server) handleSessionSubroute(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/agent/sessions/")
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(parts) != 2 || parts[1] != "events" {
+		http.NotFound(w, r)
+		return
+	}
+	srv.handleEvents(w, r, parts[0])
+}
+
+// handleEvents 以 SSE 方式推送会话事件，直到客户端断开连接或请求 ctx 被取消
+func (srv *Server) handleEvents(w http.ResponseWriter, r *http.Request, sessionID string) {
+	s, err := srv.session(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming不受支持", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch, cancel := s.subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case evt, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleApproveSubroute 分发 /v1/commands/{id}/approve
+func (srv *Server) handleApproveSubroute(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/commands/")
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(parts) != 2 || parts[1] != "approve" {
+		http.NotFound(w, r)
+		return
+	}
+	srv.handleApprove(w, r, parts[0])
+}
+
+type approveRequest struct {
+	Approve    bool   `json:"approve"`
+	ApprovedBy string `json:"approved_by,omitempty"`
+}
+
+// handleApprove 批准或拒绝一条正挂起的高危命令，唤醒 Session.step 里阻塞的那次 awaitApproval
+func (srv *Server) handleApprove(w http.ResponseWriter, r *http.Request, commandID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req approveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("请求体解析失败: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// ApprovedBy 留给调用方标注批准者身份；在守护进程还没有接入认证之前，没带就落一个占位标识，
+	// 好歹让审计记录里能看出"是走批准流程放行的"而不是空白
+	approvedBy := req.ApprovedBy
+	if approvedBy == "" {
+		approvedBy = "local-user"
+	}
+
+	if err := srv.approve(commandID, req.Approve, approvedBy); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleContext 汇报 deepsentryd 的整体状态；带 session_id 时返回该会话采集到的 SystemContext
+func (srv *Server) handleContext(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": srv.sessionCount()})
+		return
+	}
+
+	s, err := srv.session(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.ctx)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
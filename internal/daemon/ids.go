@@ -0,0 +1,17 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newID 生成一个随机的 session/command 标识，风格与 audit/config 包里已有的
+// crypto/rand 用法保持一致，避免引入新的 uuid 依赖
+func newID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		// 极端情况下熵源不可用：退化为固定前缀，保证调用方仍能拿到一个非空字符串
+		return "id-fallback"
+	}
+	return hex.EncodeToString(buf)
+}
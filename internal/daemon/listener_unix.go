@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// listen 解析 addr 并返回对应的监听器：
+//
+//	"unix:///path/to.sock" -> Unix Domain Socket
+//	其他 ("host:port")     -> 普通 TCP
+//
+// Windows 下没有 Unix Domain Socket，由 listener_windows.go 提供回退实现
+func listen(addr string) (net.Listener, error) {
+	if strings.HasPrefix(addr, "unix://") {
+		path := strings.TrimPrefix(addr, "unix://")
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("创建 socket 所在目录失败: %v", err)
+		}
+		_ = os.Remove(path) // 避免进程非正常退出遗留的 socket 文件导致 bind 失败
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		// net.Listen 创建 socket 文件时遵从进程 umask，在 /tmp 这类 world-writable 目录下
+		// 默认权限可能仍然允许同机其它用户连接；显式收紧到 0600，仅当前用户可读写
+		if err := os.Chmod(path, 0600); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("收紧 socket 权限失败: %v", err)
+		}
+		return ln, nil
+	}
+	return net.Listen("tcp", addr)
+}
@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package daemon
+
+import "net"
+
+// listen 在 Windows 下的实现：不支持命名管道，也不识别 "unix://" 前缀，addr 原样交给
+// TCP 监听，所以 "unix://..." 形式的地址（包括 DefaultSocketAddr 的返回值）在这里会监听失败，
+// 需要显式传 host:port
+func listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
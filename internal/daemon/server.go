@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"ai-edr/internal/collector"
+	"fmt"
+	"sync"
+)
+
+// Server 持有 deepsentryd 进程里全部并发会话；同一台主机可以被多名分析师
+// （或一个 Web UI + 一个 CLI 客户端）同时观察，彼此互不影响
+type Server struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+
+	pendingMu sync.Mutex
+	pending   map[string]*Session // commandID -> 所属 Session，供 /v1/commands/{id}/approve 跨会话定位
+}
+
+// NewServer 创建一个空的会话注册表
+func NewServer() *Server {
+	return &Server{
+		sessions: make(map[string]*Session),
+		pending:  make(map[string]*Session),
+	}
+}
+
+// createSession 新建一个会话并登记，sysCtx 由客户端在创建时一次性提供
+func (srv *Server) createSession(sysCtx collector.SystemContext) *Session {
+	s := newSession(sysCtx)
+	s.srv = srv
+	srv.mu.Lock()
+	srv.sessions[s.ID] = s
+	srv.mu.Unlock()
+	return s
+}
+
+// registerPending/unregisterPending 维护 commandID -> Session 的全局映射，
+// 使得 approve 请求不需要客户端额外带上 session_id
+func (srv *Server) registerPending(commandID string, s *Session) {
+	srv.pendingMu.Lock()
+	srv.pending[commandID] = s
+	srv.pendingMu.Unlock()
+}
+
+func (srv *Server) unregisterPending(commandID string) {
+	srv.pendingMu.Lock()
+	delete(srv.pending, commandID)
+	srv.pendingMu.Unlock()
+}
+
+// approve 根据全局 commandID 定位所属会话并转发批准/拒绝结果；approvedBy 是批准者身份，
+// 会一路带进 audit.Record.ApprovedBy，供日后追责
+func (srv *Server) approve(commandID string, approved bool, approvedBy string) error {
+	srv.pendingMu.Lock()
+	s, ok := srv.pending[commandID]
+	srv.pendingMu.Unlock()
+	if !ok {
+		return fmt.Errorf("待批准命令不存在或已处理: %s", commandID)
+	}
+	if !s.resolveApproval(commandID, approved, approvedBy) {
+		return fmt.Errorf("待批准命令不存在或已处理: %s", commandID)
+	}
+	return nil
+}
+
+func (srv *Server) session(id string) (*Session, error) {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	s, ok := srv.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("会话不存在: %s", id)
+	}
+	return s, nil
+}
+
+// sessionCount 供 /v1/context 汇报当前存活会话数
+func (srv *Server) sessionCount() int {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	return len(srv.sessions)
+}
@@ -0,0 +1,214 @@
+package daemon
+
+import (
+	"ai-edr/internal/analyzer"
+	"ai-edr/internal/collector"
+	"ai-edr/internal/security"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Session 持有一名分析师与 Agent 之间的完整会话状态：历史消息、系统上下文、
+// 正在运行的 Agent 循环。这些原先都装在 TUI 进程的局部变量里，现在搬到 deepsentryd
+// 常驻进程中，使得终端崩溃/重连不会丢失 LLM 会话
+type Session struct {
+	ID  string
+	ctx collector.SystemContext
+	srv *Server // 用于在 Server 级别登记/注销待批准命令，使 approve 请求无需携带 session_id
+
+	mu      sync.Mutex
+	history []analyzer.Message
+	running bool
+
+	events *eventBus
+
+	pendingMu sync.Mutex
+	pending   map[string]chan approvalResult // commandID -> 审批结果
+}
+
+// approvalResult 携带审批结果以及批准者身份，后者会写进 audit.Record.ApprovedBy
+type approvalResult struct {
+	approved   bool
+	approvedBy string
+}
+
+func newSession(sysCtx collector.SystemContext) *Session {
+	return &Session{
+		ID:      newID(),
+		ctx:     sysCtx,
+		events:  newEventBus(),
+		pending: make(map[string]chan approvalResult),
+	}
+}
+
+// subscribe 订阅本会话的事件流，返回的 cancel 必须在客户端断开时调用
+func (s *Session) subscribe() (<-chan Event, func()) {
+	id, ch := s.events.subscribe()
+	return ch, func() { s.events.unsubscribe(id) }
+}
+
+// appendUserInput 把一条用户输入（首次指令，或对上一条 final_report 的追问）接到历史末尾
+func (s *Session) appendUserInput(input string) {
+	if input == "" {
+		return
+	}
+	s.mu.Lock()
+	s.history = append(s.history, analyzer.Message{Role: "user", Content: input})
+	s.mu.Unlock()
+}
+
+// step 驱动一轮（或因自动执行低危命令而连续多轮）Agent 循环，直到：
+//   - 模型认为任务已完成 (IsFinished)
+//   - 遇到高危命令，发布 awaiting_approval 事件后挂起等待 approve/deny
+//   - ctx 被取消或出现错误
+//
+// 循环本身通过事件流对外可见，HTTP handler 只需要异步发起，不必等待完成
+func (s *Session) step(ctx context.Context) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	for {
+		partials := analyzer.RunAgentStepStream(ctx, s.ctx, &s.history)
+
+		var final analyzer.AgentResponse
+		var stepErr error
+		for p := range partials {
+			if !p.Done {
+				s.events.publish(Event{Type: "thought", Ts: now(), Data: map[string]string{
+					"thought": p.Thought, "command": p.Command, "risk_level": p.RiskLevel,
+				}})
+				continue
+			}
+			if p.Err != nil {
+				stepErr = p.Err
+				continue
+			}
+			final = p.Final
+		}
+
+		if stepErr != nil {
+			s.events.publish(Event{Type: "error", Ts: now(), Data: stepErr.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		s.history = append(s.history, analyzer.Message{Role: "assistant", Content: final.Thought})
+		s.mu.Unlock()
+
+		if final.Command == "" {
+			if final.IsFinished {
+				s.events.publish(Event{Type: "final", Ts: now(), Data: final})
+			}
+			return
+		}
+
+		recordIndex := analyzer.LastRecordIndex()
+
+		approved := true
+		var approvedBy string
+		if final.RiskLevel == "high" {
+			approved, approvedBy = s.awaitApproval(ctx, final)
+			if !approved {
+				s.events.publish(Event{Type: "denied", Ts: now(), Data: final.Command})
+				return
+			}
+			security.RecordApproval(final.Command)
+		}
+
+		out, err := security.SafeExecV3(final.Command, approvedBy)
+		s.events.publish(Event{Type: "output", Ts: now(), Data: map[string]string{
+			"command": final.Command,
+			"output":  out,
+			"error":   errString(err),
+		}})
+
+		if recErr := analyzer.RecordExec(recordIndex, final.Command, out, final.RiskLevel, final.Reason); recErr != nil {
+			s.events.publish(Event{Type: "error", Ts: now(), Data: recErr.Error()})
+		}
+
+		result := fmt.Sprintf("命令: %s\n输出:\n%s", final.Command, out)
+		if err != nil {
+			result += fmt.Sprintf("\n错误: %v", err)
+		}
+		s.mu.Lock()
+		s.history = append(s.history, analyzer.Message{Role: "user", Content: result})
+		s.mu.Unlock()
+
+		if final.IsFinished {
+			s.events.publish(Event{Type: "final", Ts: now(), Data: final})
+			return
+		}
+	}
+}
+
+// awaitApproval 挂起当前循环，等待 /v1/commands/{id}/approve 调用或 ctx 取消；
+// 返回是否批准，以及批准者身份（供调用方传给 security.SafeExecV3 写入审计记录）
+func (s *Session) awaitApproval(ctx context.Context, resp analyzer.AgentResponse) (bool, string) {
+	commandID := newID()
+	ch := make(chan approvalResult, 1)
+
+	s.pendingMu.Lock()
+	s.pending[commandID] = ch
+	s.pendingMu.Unlock()
+	if s.srv != nil {
+		s.srv.registerPending(commandID, s)
+	}
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, commandID)
+		s.pendingMu.Unlock()
+		if s.srv != nil {
+			s.srv.unregisterPending(commandID)
+		}
+	}()
+
+	s.events.publish(Event{Type: "awaiting_approval", Ts: now(), Data: map[string]string{
+		"command_id": commandID,
+		"command":    resp.Command,
+		"reason":     resp.Reason,
+	}})
+
+	select {
+	case result := <-ch:
+		return result.approved, result.approvedBy
+	case <-ctx.Done():
+		return false, ""
+	}
+}
+
+// resolveApproval 把某条挂起的高危命令标记为批准/拒绝；commandID 未找到（已超时或已处理）时返回 false
+func (s *Session) resolveApproval(commandID string, approve bool, approvedBy string) bool {
+	s.pendingMu.Lock()
+	ch, ok := s.pending[commandID]
+	s.pendingMu.Unlock()
+	if !ok {
+		return false
+	}
+	ch <- approvalResult{approved: approve, approvedBy: approvedBy}
+	return true
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// now 独立成函数而不是直接调 time.Now()，方便以后需要注入时钟做测试时替换
+func now() time.Time {
+	return time.Now()
+}
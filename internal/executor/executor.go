@@ -32,7 +32,11 @@ var Current Executor
 
 // Init 初始化执行器
 func Init(cfg config.Config) error {
-	if cfg.SSHHost != "" {
+	if len(cfg.SSHHosts) > 0 {
+		e := newFanoutExecutor(cfg)
+		Current = e
+		fmt.Printf("🔌 [模式切换] 已启用多主机扇出模式 (Fanout): %d 台主机\n", len(cfg.SSHHosts))
+	} else if cfg.SSHHost != "" {
 		e, err := newSSHExecutor(cfg)
 		if err != nil {
 			return err
@@ -185,10 +189,25 @@ type SSHExecutor struct {
 	mu         sync.Mutex
 }
 
-func newSSHExecutor(cfg config.Config) (*SSHExecutor, error) {
+// sshDialSpec 描述建立一条 SSH 连接所需的最小参数集
+// 单主机 SSHExecutor 与 FanoutExecutor 的连接池都基于它来拨号，避免逻辑重复
+type sshDialSpec struct {
+	Addr     string // host:port
+	User     string
+	Password string
+	KeyPath  string
+
+	// KnownHostsPath / StrictMode 为空时分别回退到 config 包的默认值与 "strict"
+	KnownHostsPath string
+	StrictMode     string
+}
+
+// dialSSHClient 根据 sshDialSpec 建立底层 *ssh.Client
+// 主机密钥校验交给托管的 known_hosts 存储 (见 hostkey.go)，不再信任任意密钥
+func dialSSHClient(spec sshDialSpec) (*ssh.Client, error) {
 	var authMethods []ssh.AuthMethod
-	if cfg.SSHKeyPath != "" {
-		key, err := os.ReadFile(cfg.SSHKeyPath)
+	if spec.KeyPath != "" {
+		key, err := os.ReadFile(spec.KeyPath)
 		if err != nil {
 			return nil, fmt.Errorf("读取私钥失败: %v", err)
 		}
@@ -198,56 +217,94 @@ func newSSHExecutor(cfg config.Config) (*SSHExecutor, error) {
 		}
 		authMethods = append(authMethods, ssh.PublicKeys(signer))
 	} else {
-		authMethods = append(authMethods, ssh.Password(cfg.SSHPassword))
+		authMethods = append(authMethods, ssh.Password(spec.Password))
+	}
+
+	knownHostsPath := spec.KnownHostsPath
+	if knownHostsPath == "" {
+		knownHostsPath = config.GlobalConfig.SSHKnownHostsPath
+	}
+	strictMode := spec.StrictMode
+	if strictMode == "" {
+		strictMode = config.GlobalConfig.StrictHostKeyChecking
+	}
+
+	verifier, err := NewHostKeyVerifier(knownHostsPath, strictMode)
+	if err != nil {
+		return nil, fmt.Errorf("初始化主机密钥校验失败: %v", err)
 	}
 
 	sshConfig := &ssh.ClientConfig{
-		User:            cfg.SSHUser,
+		User:            spec.User,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: verifier.Callback(),
 		Timeout:         10 * time.Second,
 	}
 
-	client, err := ssh.Dial("tcp", cfg.SSHHost, sshConfig)
+	client, err := ssh.Dial("tcp", spec.Addr, sshConfig)
 	if err != nil {
 		return nil, fmt.Errorf("SSH连接失败: %v", err)
 	}
+	return client, nil
+}
 
-	sftpClient, err := sftp.NewClient(client)
-	if err != nil {
-		client.Close()
-		return nil, fmt.Errorf("SFTP 初始化失败: %v", err)
-	}
-
+// openInteractiveShell 在已建立的 client 上启动一个持久的交互式 Shell
+// 供需要维持会话状态（cd、环境变量等）的调用方复用，例如 SSHExecutor 与 FanoutExecutor 的连接池
+func openInteractiveShell(client *ssh.Client) (*ssh.Session, io.WriteCloser, *bufio.Reader, error) {
 	session, err := client.NewSession()
 	if err != nil {
-		sftpClient.Close()
-		client.Close()
-		return nil, fmt.Errorf("Session 创建失败: %v", err)
+		return nil, nil, nil, fmt.Errorf("Session 创建失败: %v", err)
 	}
 
 	stdin, err := session.StdinPipe()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	stdout, err := session.StdoutPipe()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	session.Stderr = session.Stdout
 
 	if err := session.Start("/bin/bash"); err != nil {
 		if err := session.Start("/bin/sh"); err != nil {
-			return nil, fmt.Errorf("无法启动远程Shell: %v", err)
+			return nil, nil, nil, fmt.Errorf("无法启动远程Shell: %v", err)
 		}
 	}
 
+	return session, stdin, bufio.NewReader(stdout), nil
+}
+
+func newSSHExecutor(cfg config.Config) (*SSHExecutor, error) {
+	client, err := dialSSHClient(sshDialSpec{
+		Addr:     cfg.SSHHost,
+		User:     cfg.SSHUser,
+		Password: cfg.SSHPassword,
+		KeyPath:  cfg.SSHKeyPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("SFTP 初始化失败: %v", err)
+	}
+
+	session, stdin, stdout, err := openInteractiveShell(client)
+	if err != nil {
+		sftpClient.Close()
+		client.Close()
+		return nil, err
+	}
+
 	exe := &SSHExecutor{
 		client:     client,
 		session:    session,
 		sftpClient: sftpClient,
 		stdin:      stdin,
-		stdout:     bufio.NewReader(stdout),
+		stdout:     stdout,
 	}
 
 	exe.Run("export TERM=xterm; export LANG=en_US.UTF-8")
@@ -286,19 +343,11 @@ func (s *SSHExecutor) Run(cmdStr string) (string, error) {
 	}
 
 	if strings.HasPrefix(cmdStr, "upload ") {
-		parts := strings.Fields(cmdStr)
-		if len(parts) != 3 {
-			return "", fmt.Errorf("用法: upload <本地文件> <远程路径>")
-		}
-		return s.uploadFile(parts[1], parts[2])
+		return s.dispatchTransfer("upload", strings.TrimPrefix(cmdStr, "upload "))
 	}
 
 	if strings.HasPrefix(cmdStr, "download ") {
-		parts := strings.Fields(cmdStr)
-		if len(parts) != 3 {
-			return "", fmt.Errorf("用法: download <远程文件> <本地路径>")
-		}
-		return s.downloadFile(parts[1], parts[2])
+		return s.dispatchTransfer("download", strings.TrimPrefix(cmdStr, "download "))
 	}
 
 	endMarker := fmt.Sprintf("__END_%d__", time.Now().UnixNano())
@@ -323,52 +372,6 @@ func (s *SSHExecutor) Run(cmdStr string) (string, error) {
 	return strings.TrimSpace(strings.Join(outputLines, "")), nil
 }
 
-func (s *SSHExecutor) uploadFile(localPath, remotePath string) (string, error) {
-	srcFile, err := os.Open(localPath)
-	if err != nil {
-		return "", fmt.Errorf("无法打开本地文件: %v", err)
-	}
-	defer srcFile.Close()
-
-	s.sftpClient.MkdirAll(filepath.Dir(remotePath))
-
-	dstFile, err := s.sftpClient.Create(remotePath)
-	if err != nil {
-		return "", fmt.Errorf("无法创建远程文件: %v", err)
-	}
-	defer dstFile.Close()
-
-	n, err := io.Copy(dstFile, srcFile)
-	if err != nil {
-		return "", fmt.Errorf("上传传输失败: %v", err)
-	}
-	return fmt.Sprintf("✅ 上传成功 (Bytes: %d): %s -> %s", n, localPath, remotePath), nil
-}
-
-func (s *SSHExecutor) downloadFile(remotePath, localPath string) (string, error) {
-	srcFile, err := s.sftpClient.Open(remotePath)
-	if err != nil {
-		return "", fmt.Errorf("无法打开远程文件: %v", err)
-	}
-	defer srcFile.Close()
-
-	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
-		return "", fmt.Errorf("创建本地目录失败: %v", err)
-	}
-
-	dstFile, err := os.Create(localPath)
-	if err != nil {
-		return "", fmt.Errorf("无法创建本地文件: %v", err)
-	}
-	defer dstFile.Close()
-
-	n, err := io.Copy(dstFile, srcFile)
-	if err != nil {
-		return "", fmt.Errorf("下载传输失败: %v", err)
-	}
-	return fmt.Sprintf("✅ 下载成功 (Bytes: %d): %s -> %s", n, remotePath, localPath), nil
-}
-
 func (s *SSHExecutor) IsRemote() bool { return true }
 
 func (s *SSHExecutor) Close() {
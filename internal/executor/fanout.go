@@ -0,0 +1,286 @@
+package executor
+
+import (
+	"ai-edr/internal/config"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFanoutConcurrency 扇出执行的默认并发上限（每次 Run 同时连接的主机数）
+const defaultFanoutConcurrency = 50
+
+// defaultHostTimeout 单台主机单条命令的默认超时时间
+const defaultHostTimeout = 30 * time.Second
+
+// hostResult 记录某台主机上一次命令执行的结果
+type hostResult struct {
+	Stdout   string        `json:"stdout"`
+	Err      string        `json:"err,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// fanoutConn 是单台主机的持久连接，被连接池复用，省去每次 Run 都重新握手的开销
+type fanoutConn struct {
+	mu sync.Mutex
+	*SSHExecutor
+}
+
+// FanoutExecutor 实现 Executor 接口，将一次 Run(cmd) 并发分发到配置中的多台主机
+// 设计上镜像常见的 SSH 广播/批量执行工具：有界 worker pool + 逐主机超时 + 结果聚合表
+type FanoutExecutor struct {
+	hosts       []config.HostSpec
+	concurrency int
+	timeout     time.Duration
+
+	poolMu sync.Mutex
+	pool   map[string]*fanoutConn // hostID -> 已建立的持久连接
+}
+
+func newFanoutExecutor(cfg config.Config) *FanoutExecutor {
+	return &FanoutExecutor{
+		hosts:       cfg.SSHHosts,
+		concurrency: defaultFanoutConcurrency,
+		timeout:     defaultHostTimeout,
+		pool:        make(map[string]*fanoutConn),
+	}
+}
+
+// hostID 为 HostSpec 生成一个稳定标识，用于结果聚合与连接池索引
+func hostID(h config.HostSpec) string {
+	if h.User != "" {
+		return fmt.Sprintf("%s@%s", h.User, h.Host)
+	}
+	return h.Host
+}
+
+// hasTag 判断主机是否携带指定标签
+func hasTag(h config.HostSpec, tag string) bool {
+	for _, t := range h.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTagFilter 解析 "@web: uptime" 这类命令前缀，拆出标签与真正要执行的命令
+// 不带 "@tag:" 前缀的命令视为发往全部主机
+func parseTagFilter(cmdStr string) (tag string, cmd string) {
+	cmdStr = strings.TrimSpace(cmdStr)
+	if !strings.HasPrefix(cmdStr, "@") {
+		return "", cmdStr
+	}
+	idx := strings.Index(cmdStr, ":")
+	if idx == -1 {
+		return "", cmdStr
+	}
+	tag = strings.TrimSpace(cmdStr[1:idx])
+	cmd = strings.TrimSpace(cmdStr[idx+1:])
+	return tag, cmd
+}
+
+// Run 实现 Executor 接口：解析 "@tag: cmd" 过滤语法后扇出到匹配的主机，返回聚合后的表格
+func (f *FanoutExecutor) Run(cmdStr string) (string, error) {
+	tag, cmd := parseTagFilter(cmdStr)
+
+	targets := f.hosts
+	if tag != "" {
+		targets = nil
+		for _, h := range f.hosts {
+			if hasTag(h, tag) {
+				targets = append(targets, h)
+			}
+		}
+		if len(targets) == 0 {
+			return "", fmt.Errorf("未找到匹配标签 @%s 的主机", tag)
+		}
+	}
+
+	results := f.dispatch(targets, cmd)
+	return renderResults(results), nil
+}
+
+// RunOn 供程序化调用：只向携带 tag 的主机广播 cmd，返回原始的 hostID -> Result 映射
+func (f *FanoutExecutor) RunOn(tag, cmd string) (map[string]hostResult, error) {
+	var targets []config.HostSpec
+	for _, h := range f.hosts {
+		if hasTag(h, tag) {
+			targets = append(targets, h)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("未找到匹配标签 @%s 的主机", tag)
+	}
+	return f.dispatch(targets, cmd), nil
+}
+
+// dispatch 是扇出核心：有界 worker pool 并发执行，逐主机超时，结果汇总到一张 map 里
+func (f *FanoutExecutor) dispatch(targets []config.HostSpec, cmd string) map[string]hostResult {
+	results := make(map[string]hostResult, len(targets))
+	var resultsMu sync.Mutex
+
+	sem := make(chan struct{}, f.concurrency)
+	var wg sync.WaitGroup
+
+	for _, h := range targets {
+		h := h
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			out, err := f.runOnHost(h, cmd)
+			r := hostResult{Stdout: out, Duration: time.Since(start)}
+			if err != nil {
+				r.Err = err.Error()
+			}
+
+			resultsMu.Lock()
+			results[hostID(h)] = r
+			resultsMu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// runOnHost 复用（或建立）一条持久连接，在超时预算内执行单条命令
+func (f *FanoutExecutor) runOnHost(h config.HostSpec, cmd string) (string, error) {
+	conn, err := f.connFor(h)
+	if err != nil {
+		return "", err
+	}
+
+	type outcome struct {
+		out string
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+		out, err := conn.SSHExecutor.Run(cmd)
+		done <- outcome{out, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.out, o.err
+	case <-time.After(f.timeout):
+		// 命令本身（conn.SSHExecutor.Run）没有办法被取消，仍在后台持有 conn.mu 运行。
+		// 如果不把这条连接逐出连接池，它会一直占着 conn.mu，后续发往同一台主机的每条
+		// 命令都会永久阻塞在 conn.mu.Lock() 上。关掉底层 transport 让卡住的 Run 尽快
+		// 因读错误退出，逐出动作本身不需要拿 conn.mu，不会被卡住的 goroutine 挡住
+		f.evictConn(h)
+		return "", fmt.Errorf("主机 %s 执行超时 (%s)，连接已重置", hostID(h), f.timeout)
+	}
+}
+
+// evictConn 把某台主机的连接移出连接池并关闭底层 transport，下一次 connFor 会重新拨号。
+// 用于清理因超时而可能卡死的连接，而不是任由它永久占用 conn.mu
+func (f *FanoutExecutor) evictConn(h config.HostSpec) {
+	id := hostID(h)
+
+	f.poolMu.Lock()
+	c, ok := f.pool[id]
+	if ok {
+		delete(f.pool, id)
+	}
+	f.poolMu.Unlock()
+
+	if ok {
+		go c.SSHExecutor.Close()
+	}
+}
+
+// connFor 从连接池取出或新建一台主机的持久连接（keep-alive，后续调用复用同一条 Shell 会话）
+func (f *FanoutExecutor) connFor(h config.HostSpec) (*fanoutConn, error) {
+	id := hostID(h)
+
+	f.poolMu.Lock()
+	if c, ok := f.pool[id]; ok {
+		f.poolMu.Unlock()
+		return c, nil
+	}
+	f.poolMu.Unlock()
+
+	addr := h.Host
+	if h.Port != 0 && !strings.Contains(addr, ":") {
+		addr = fmt.Sprintf("%s:%d", h.Host, h.Port)
+	}
+
+	exe, err := newSSHExecutor(config.Config{
+		SSHHost:     addr,
+		SSHUser:     h.User,
+		SSHPassword: h.Password,
+		SSHKeyPath:  h.KeyPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("连接主机 %s 失败: %v", id, err)
+	}
+
+	c := &fanoutConn{SSHExecutor: exe}
+
+	f.poolMu.Lock()
+	f.pool[id] = c
+	f.poolMu.Unlock()
+
+	return c, nil
+}
+
+// renderResults 把逐主机结果渲染成一张人类可读的表格摘要，经 Run 返回给调用方
+func renderResults(results map[string]hostResult) string {
+	ids := make([]string, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 [Fanout 执行结果] 共 %d 台主机\n", len(ids))
+	for _, id := range ids {
+		r := results[id]
+		status := "✅"
+		if r.Err != "" {
+			status = "❌"
+		}
+		fmt.Fprintf(&b, "\n%s %s (耗时 %s):\n%s\n", status, id, r.Duration.Round(time.Millisecond), r.Stdout)
+		if r.Err != "" {
+			fmt.Fprintf(&b, "错误: %s\n", r.Err)
+		}
+	}
+	return b.String()
+}
+
+// IsRemote 扇出执行器本质上操作的是远程主机集合
+func (f *FanoutExecutor) IsRemote() bool { return true }
+
+// Hosts 返回扇出配置的全部主机，供 baseline 等子系统按主机逐一调度
+func (f *FanoutExecutor) Hosts() []config.HostSpec {
+	return f.hosts
+}
+
+// HostExecutor 返回（或建立）连接池中某台主机的持久连接，暴露给 baseline 等需要按主机单独下发多条命令的子系统
+func (f *FanoutExecutor) HostExecutor(h config.HostSpec) (Executor, error) {
+	conn, err := f.connFor(h)
+	if err != nil {
+		return nil, err
+	}
+	return conn.SSHExecutor, nil
+}
+
+// Close 关闭连接池中所有已建立的持久连接
+func (f *FanoutExecutor) Close() {
+	f.poolMu.Lock()
+	defer f.poolMu.Unlock()
+	for id, c := range f.pool {
+		c.SSHExecutor.Close()
+		delete(f.pool, id)
+	}
+}
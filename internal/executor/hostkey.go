@@ -0,0 +1,239 @@
+package executor
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// 主机密钥校验策略，对应 config.StrictHostKeyChecking
+const (
+	HostKeyModeStrict    = "strict"
+	HostKeyModeAsk       = "ask"
+	HostKeyModeAcceptNew = "accept-new"
+	HostKeyModeOff       = "off"
+)
+
+// hostKeyMeta 记录每条 known_hosts 条目的首见时间，供审计查阅（`ssh trust` 只打印指纹，不展示它）
+// knownhosts 文件本身不携带时间戳，所以单独维护一份 sidecar JSON
+type hostKeyMeta struct {
+	FirstSeen map[string]time.Time `json:"first_seen"` // key: SHA256 指纹
+}
+
+// HostKeyVerifier 基于托管的 known_hosts 文件实现严格的主机密钥校验
+// 取代此前 SSHExecutor 里的 ssh.InsecureIgnoreHostKey()
+type HostKeyVerifier struct {
+	path string
+	mode string
+
+	mu       sync.Mutex
+	callback ssh.HostKeyCallback
+	metaPath string
+	meta     hostKeyMeta
+}
+
+// metaPathFor known_hosts 路径派生出的 sidecar 元数据文件路径
+func metaPathFor(knownHostsPath string) string {
+	return knownHostsPath + ".meta.json"
+}
+
+// NewHostKeyVerifier 加载（或创建）托管的 known_hosts 文件，返回一个可用的校验器
+func NewHostKeyVerifier(path, mode string) (*HostKeyVerifier, error) {
+	if mode == "" {
+		mode = HostKeyModeStrict
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("创建 known_hosts 目录失败: %v", err)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600); err != nil {
+			return nil, fmt.Errorf("初始化 known_hosts 失败: %v", err)
+		} else {
+			f.Close()
+		}
+	}
+
+	cb, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("解析 known_hosts 失败: %v", err)
+	}
+
+	v := &HostKeyVerifier{
+		path:     path,
+		mode:     mode,
+		callback: cb,
+		metaPath: metaPathFor(path),
+		meta:     hostKeyMeta{FirstSeen: make(map[string]time.Time)},
+	}
+	v.loadMeta()
+	return v, nil
+}
+
+func (v *HostKeyVerifier) loadMeta() {
+	data, err := os.ReadFile(v.metaPath)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &v.meta)
+	if v.meta.FirstSeen == nil {
+		v.meta.FirstSeen = make(map[string]time.Time)
+	}
+}
+
+func (v *HostKeyVerifier) saveMeta() error {
+	data, err := json.MarshalIndent(v.meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(v.metaPath, data, 0600)
+}
+
+// Fingerprint 返回密钥的 SHA256 指纹，格式与 ssh-keygen -l 一致
+func Fingerprint(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// Callback 返回可直接用于 ssh.ClientConfig.HostKeyCallback 的校验函数
+func (v *HostKeyVerifier) Callback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return v.verify(hostname, key)
+	}
+}
+
+func (v *HostKeyVerifier) verify(hostname string, key ssh.PublicKey) error {
+	if v.mode == HostKeyModeOff {
+		return nil
+	}
+
+	err := v.callback(hostname, nil, key)
+	if err == nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if strings.Contains(err.Error(), "is not a key error") {
+		// knownhosts 内部类型断言失败时退化为原样返回
+		return err
+	}
+	if asKeyErr(err, &keyErr) && len(keyErr.Want) > 0 {
+		// 主机名已有记录，但密钥不匹配 —— 无论哪种策略都视为中间人风险，直接拒绝
+		return fmt.Errorf("⚠️ 主机密钥不匹配 (可能存在中间人攻击): %s 指纹 %s 与已记录的不一致", hostname, Fingerprint(key))
+	}
+
+	// 走到这里说明是“未知主机”（knownhosts.KeyError 且 Want 为空）
+	switch v.mode {
+	case HostKeyModeStrict:
+		return fmt.Errorf("未知主机 %s (指纹 %s)，strict 模式下拒绝连接；可运行 `deepsentry ssh trust %s` 预先信任", hostname, Fingerprint(key), hostname)
+	case HostKeyModeAcceptNew:
+		return v.trust(hostname, key)
+	case HostKeyModeAsk:
+		if !v.promptAccept(hostname, key) {
+			return fmt.Errorf("用户拒绝信任主机 %s", hostname)
+		}
+		return v.trust(hostname, key)
+	default:
+		return fmt.Errorf("未知的 StrictHostKeyChecking 取值: %s", v.mode)
+	}
+}
+
+// asKeyErr 是 errors.As 的轻量替代，避免引入额外依赖仅为一次类型断言
+func asKeyErr(err error, target **knownhosts.KeyError) bool {
+	if keyErr, ok := err.(*knownhosts.KeyError); ok {
+		*target = keyErr
+		return true
+	}
+	return false
+}
+
+// promptAccept 在 ask 模式下向用户展示指纹并等待确认
+func (v *HostKeyVerifier) promptAccept(hostname string, key ssh.PublicKey) bool {
+	fmt.Printf("🔑 未知主机 %s\n   密钥指纹: %s\n是否信任并写入 known_hosts? [y/N] ", hostname, Fingerprint(key))
+	reader := bufio.NewReader(os.Stdin)
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
+
+// trust 将主机密钥追加写入 known_hosts，并记录首见时间
+func (v *HostKeyVerifier) trust(hostname string, key ssh.PublicKey) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f, err := os.OpenFile(v.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("写入 known_hosts 失败: %v", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{hostname}, key)
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("写入 known_hosts 失败: %v", err)
+	}
+
+	v.meta.FirstSeen[Fingerprint(key)] = time.Now()
+	if err := v.saveMeta(); err != nil {
+		return fmt.Errorf("写入 known_hosts 元数据失败: %v", err)
+	}
+
+	// 刷新内存中的回调，让本次连接立即认得新写入的条目
+	cb, err := knownhosts.New(v.path)
+	if err == nil {
+		v.callback = cb
+	}
+
+	fmt.Printf("✅ 已信任主机 %s (指纹 %s)\n", hostname, Fingerprint(key))
+	return nil
+}
+
+// FetchHostKey 仅做密钥交换（不完成认证），用于 `deepsentry ssh trust <host>` 预先抓取公钥
+func FetchHostKey(addr string) (ssh.PublicKey, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	var captured ssh.PublicKey
+	cfg := &ssh.ClientConfig{
+		User: "deepsentry-trust-probe",
+		Auth: []ssh.AuthMethod{ssh.Password("")},
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			captured = key
+			return nil
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	client, err := ssh.Dial("tcp", addr, cfg)
+	if client != nil {
+		client.Close()
+	}
+	if captured == nil {
+		return nil, fmt.Errorf("未能获取主机密钥: %v", err)
+	}
+	return captured, nil
+}
+
+// TrustHost 供 `deepsentry ssh trust <host>` 调用：抓取目标主机密钥并写入 known_hosts
+func TrustHost(addr, knownHostsPath string) error {
+	key, err := FetchHostKey(addr)
+	if err != nil {
+		return err
+	}
+	v, err := NewHostKeyVerifier(knownHostsPath, HostKeyModeAcceptNew)
+	if err != nil {
+		return err
+	}
+	return v.trust(addr, key)
+}
@@ -0,0 +1,525 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressReporter 接收一次传输过程中的进度回调，供 upload/download -r 展示百分比/速率/ETA
+type ProgressReporter interface {
+	OnProgress(p TransferProgress)
+}
+
+// TransferProgress 描述单个文件某一时刻的传输进度
+type TransferProgress struct {
+	Path        string // 当前正在传输的文件（相对路径）
+	BytesDone   int64
+	BytesTotal  int64 // 未知大小（如 resume 前无法确定）时为 0
+	BytesPerSec float64
+	ETA         time.Duration // 未知大小或速率为 0 时为 0
+}
+
+// noopProgressReporter 不打印任何内容，单文件 upload/download（无 -r）沿用旧的静默行为
+type noopProgressReporter struct{}
+
+func (noopProgressReporter) OnProgress(TransferProgress) {}
+
+// consoleProgressReporter 把进度节流打印到标准输出，递归传输 (-r) 默认使用它
+type consoleProgressReporter struct {
+	mu        sync.Mutex
+	lastPrint time.Time
+}
+
+// NewConsoleProgressReporter 返回一个打印到控制台的 ProgressReporter
+func NewConsoleProgressReporter() ProgressReporter {
+	return &consoleProgressReporter{}
+}
+
+func (c *consoleProgressReporter) OnProgress(p TransferProgress) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	// 每个文件至少打印一次（done==total 时），否则按 200ms 节流，避免刷屏
+	isDone := p.BytesTotal > 0 && p.BytesDone >= p.BytesTotal
+	if !isDone && now.Sub(c.lastPrint) < 200*time.Millisecond {
+		return
+	}
+	c.lastPrint = now
+
+	percent := "?"
+	if p.BytesTotal > 0 {
+		percent = fmt.Sprintf("%.1f%%", float64(p.BytesDone)/float64(p.BytesTotal)*100)
+	}
+	eta := "-"
+	if p.ETA > 0 {
+		eta = p.ETA.Round(time.Second).String()
+	}
+	fmt.Printf("⏳ %s: %s (%s/s, ETA %s)\n", p.Path, percent, humanBytes(p.BytesPerSec), eta)
+}
+
+func humanBytes(n float64) string {
+	switch {
+	case n >= 1024*1024*1024:
+		return fmt.Sprintf("%.1fGB", n/(1024*1024*1024))
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", n/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1fKB", n/1024)
+	default:
+		return fmt.Sprintf("%.0fB", n)
+	}
+}
+
+// TransferOptions 控制单次 upload/download（及其递归版本）的行为
+type TransferOptions struct {
+	Recursive bool
+	Resume    bool
+	// LimitBytesPerSec 为 0 表示不限速
+	LimitBytesPerSec int64
+	Reporter         ProgressReporter
+}
+
+func (o TransferOptions) reporter() ProgressReporter {
+	if o.Reporter != nil {
+		return o.Reporter
+	}
+	return noopProgressReporter{}
+}
+
+// transferArgs 是 "upload"/"download" 命令行解析出的结构化参数
+type transferArgs struct {
+	Recursive bool
+	Resume    bool
+	LimitBPS  int64
+	Src       string
+	Dst       string
+}
+
+// parseTransferArgs 解析 `upload [-r] [--resume] [--limit 2MB/s] <src> <dst>` 风格的参数
+func parseTransferArgs(parts []string) (transferArgs, error) {
+	var a transferArgs
+	var positional []string
+
+	for i := 0; i < len(parts); i++ {
+		switch parts[i] {
+		case "-r", "--recursive":
+			a.Recursive = true
+		case "--resume":
+			a.Resume = true
+		case "--limit":
+			if i+1 >= len(parts) {
+				return a, fmt.Errorf("--limit 需要一个速率参数，如 2MB/s")
+			}
+			i++
+			bps, err := parseBandwidth(parts[i])
+			if err != nil {
+				return a, err
+			}
+			a.LimitBPS = bps
+		default:
+			positional = append(positional, parts[i])
+		}
+	}
+
+	if len(positional) != 2 {
+		return a, fmt.Errorf("用法: upload/download [-r] [--resume] [--limit <速率>] <src> <dst>")
+	}
+	a.Src, a.Dst = positional[0], positional[1]
+	return a, nil
+}
+
+// parseBandwidth 解析 "2MB/s"、"512KB/s"、"100" 这类带宽限制，返回字节/秒
+func parseBandwidth(s string) (int64, error) {
+	raw := strings.TrimSpace(s)
+	upper := strings.ToUpper(raw)
+	upper = strings.TrimSuffix(upper, "/S")
+
+	multiplier := float64(1)
+	switch {
+	case strings.HasSuffix(upper, "GB"):
+		multiplier = 1024 * 1024 * 1024
+		upper = strings.TrimSuffix(upper, "GB")
+	case strings.HasSuffix(upper, "MB"):
+		multiplier = 1024 * 1024
+		upper = strings.TrimSuffix(upper, "MB")
+	case strings.HasSuffix(upper, "KB"):
+		multiplier = 1024
+		upper = strings.TrimSuffix(upper, "KB")
+	case strings.HasSuffix(upper, "B"):
+		upper = strings.TrimSuffix(upper, "B")
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的带宽限制 %q，期望形如 2MB/s", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+// throttledCounter 包装 io.Writer，在写入时统计字节数、驱动进度回调并做令牌桶限速
+type throttledCounter struct {
+	w           io.Writer
+	path        string
+	total       int64
+	done        int64
+	limitBPS    int64
+	reporter    ProgressReporter
+	started     time.Time
+	lastBurstAt time.Time
+}
+
+func newThrottledCounter(w io.Writer, path string, total int64, opts TransferOptions) *throttledCounter {
+	now := time.Now()
+	return &throttledCounter{
+		w:           w,
+		path:        path,
+		total:       total,
+		limitBPS:    opts.LimitBytesPerSec,
+		reporter:    opts.reporter(),
+		started:     now,
+		lastBurstAt: now,
+	}
+}
+
+func (t *throttledCounter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if n > 0 {
+		t.done += int64(n)
+		t.throttle(n)
+		t.report()
+	}
+	return n, err
+}
+
+// throttle 简单的令牌桶限速：按本次写入量换算应耗时，超出部分 sleep 补足
+func (t *throttledCounter) throttle(n int) {
+	if t.limitBPS <= 0 {
+		return
+	}
+	expected := time.Duration(float64(n) / float64(t.limitBPS) * float64(time.Second))
+	elapsed := time.Since(t.lastBurstAt)
+	if expected > elapsed {
+		time.Sleep(expected - elapsed)
+	}
+	t.lastBurstAt = time.Now()
+}
+
+func (t *throttledCounter) report() {
+	elapsed := time.Since(t.started).Seconds()
+	var bps float64
+	if elapsed > 0 {
+		bps = float64(t.done) / elapsed
+	}
+	var eta time.Duration
+	if bps > 0 && t.total > t.done {
+		eta = time.Duration(float64(t.total-t.done)/bps) * time.Second
+	}
+	t.reporter.OnProgress(TransferProgress{
+		Path:        t.path,
+		BytesDone:   t.done,
+		BytesTotal:  t.total,
+		BytesPerSec: bps,
+		ETA:         eta,
+	})
+}
+
+// sha256File 计算本地文件的 SHA256，用于传输后与远端比对完整性
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// shellQuoteSingle 把一个参数用 POSIX 单引号安全地引起来，供拼接远端 shell 命令使用。
+// Go 的 %q 做的是 Go 字符串转义，不是 shell 转义，不能防住 $()/反引号/分号等元字符
+func shellQuoteSingle(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// remoteSHA256 通过一次性 SSH session 在远端执行 sha256sum（兼容 BSD 的 shasum -a 256）
+func (s *SSHExecutor) remoteSHA256(remotePath string) (string, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("创建校验 session 失败: %v", err)
+	}
+	defer session.Close()
+
+	quoted := shellQuoteSingle(remotePath)
+	cmd := fmt.Sprintf("sha256sum %s 2>/dev/null || shasum -a 256 %s", quoted, quoted)
+	out, err := session.CombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("远端计算 SHA256 失败: %v (%s)", err, strings.TrimSpace(string(out)))
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("远端未返回 SHA256 结果: %s", remotePath)
+	}
+	return fields[0], nil
+}
+
+// uploadFileResume 上传单个文件：支持 --resume / --limit / 进度回调 / 双端 SHA256 校验
+func (s *SSHExecutor) uploadFileResume(localPath, remotePath string, opts TransferOptions) (string, error) {
+	srcFile, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("无法打开本地文件: %v", err)
+	}
+	defer srcFile.Close()
+
+	info, err := srcFile.Stat()
+	if err != nil {
+		return "", fmt.Errorf("无法读取本地文件信息: %v", err)
+	}
+
+	s.sftpClient.MkdirAll(filepath.Dir(remotePath))
+
+	var offset int64
+	openFlags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if opts.Resume {
+		if remoteInfo, err := s.sftpClient.Stat(remotePath); err == nil {
+			offset = remoteInfo.Size()
+		}
+		openFlags = os.O_WRONLY | os.O_CREATE
+	}
+	if offset > info.Size() {
+		// 远端文件比本地还大，视为损坏/不一致，放弃续传从头开始
+		offset = 0
+		openFlags |= os.O_TRUNC
+	}
+
+	dstFile, err := s.sftpClient.OpenFile(remotePath, openFlags)
+	if err != nil {
+		return "", fmt.Errorf("无法创建远程文件: %v", err)
+	}
+	defer dstFile.Close()
+
+	if offset > 0 {
+		if _, err := srcFile.Seek(offset, io.SeekStart); err != nil {
+			return "", fmt.Errorf("本地文件 seek 失败: %v", err)
+		}
+		if _, err := dstFile.Seek(offset, io.SeekStart); err != nil {
+			return "", fmt.Errorf("远程文件 seek 失败: %v", err)
+		}
+	}
+
+	counter := newThrottledCounter(dstFile, remotePath, info.Size(), opts)
+	counter.done = offset
+	n, err := io.Copy(counter, srcFile)
+	if err != nil {
+		return "", fmt.Errorf("上传传输失败: %v", err)
+	}
+
+	localSum, err := sha256File(localPath)
+	if err != nil {
+		return "", fmt.Errorf("本地 SHA256 计算失败: %v", err)
+	}
+	remoteSum, err := s.remoteSHA256(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("远端 SHA256 计算失败: %v", err)
+	}
+	if localSum != remoteSum {
+		return "", fmt.Errorf("完整性校验失败: %s 本地 %s 远端 %s", remotePath, localSum, remoteSum)
+	}
+
+	return fmt.Sprintf("✅ 上传成功并通过 SHA256 校验 (Bytes: %d, 续传起点: %d): %s -> %s", n, offset, localPath, remotePath), nil
+}
+
+// downloadFileResume 下载单个文件：支持 --resume / --limit / 进度回调 / 双端 SHA256 校验
+func (s *SSHExecutor) downloadFileResume(remotePath, localPath string, opts TransferOptions) (string, error) {
+	srcFile, err := s.sftpClient.Open(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("无法打开远程文件: %v", err)
+	}
+	defer srcFile.Close()
+
+	remoteInfo, err := srcFile.Stat()
+	if err != nil {
+		return "", fmt.Errorf("无法读取远程文件信息: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return "", fmt.Errorf("创建本地目录失败: %v", err)
+	}
+
+	var offset int64
+	openFlags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if opts.Resume {
+		if localInfo, err := os.Stat(localPath); err == nil {
+			offset = localInfo.Size()
+		}
+		openFlags = os.O_WRONLY | os.O_CREATE
+	}
+	if offset > remoteInfo.Size() {
+		offset = 0
+		openFlags |= os.O_TRUNC
+	}
+
+	dstFile, err := os.OpenFile(localPath, openFlags, 0644)
+	if err != nil {
+		return "", fmt.Errorf("无法创建本地文件: %v", err)
+	}
+	defer dstFile.Close()
+
+	if offset > 0 {
+		if _, err := srcFile.Seek(offset, io.SeekStart); err != nil {
+			return "", fmt.Errorf("远程文件 seek 失败: %v", err)
+		}
+		if _, err := dstFile.Seek(offset, io.SeekStart); err != nil {
+			return "", fmt.Errorf("本地文件 seek 失败: %v", err)
+		}
+	}
+
+	counter := newThrottledCounter(dstFile, remotePath, remoteInfo.Size(), opts)
+	counter.done = offset
+	n, err := io.Copy(counter, srcFile)
+	if err != nil {
+		return "", fmt.Errorf("下载传输失败: %v", err)
+	}
+
+	remoteSum, err := s.remoteSHA256(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("远端 SHA256 计算失败: %v", err)
+	}
+	localSum, err := sha256File(localPath)
+	if err != nil {
+		return "", fmt.Errorf("本地 SHA256 计算失败: %v", err)
+	}
+	if localSum != remoteSum {
+		return "", fmt.Errorf("完整性校验失败: %s 本地 %s 远端 %s", localPath, localSum, remoteSum)
+	}
+
+	return fmt.Sprintf("✅ 下载成功并通过 SHA256 校验 (Bytes: %d, 续传起点: %d): %s -> %s", n, offset, remotePath, localPath), nil
+}
+
+// UploadDir 递归上传本地目录到远端，镜像目录结构；单个文件的行为与 uploadFileResume 一致
+func (s *SSHExecutor) UploadDir(localDir, remoteDir string, opts TransferOptions) (string, error) {
+	var uploaded int
+	err := filepath.Walk(localDir, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(remoteDir, filepath.ToSlash(rel))
+		if _, err := s.uploadFileResume(p, remotePath, opts); err != nil {
+			return fmt.Errorf("%s: %v", rel, err)
+		}
+		uploaded++
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("递归上传失败: %v", err)
+	}
+	return fmt.Sprintf("✅ 递归上传完成 (%d 个文件): %s -> %s", uploaded, localDir, remoteDir), nil
+}
+
+// DownloadDir 递归下载远端目录到本地，镜像目录结构；单个文件的行为与 downloadFileResume 一致
+func (s *SSHExecutor) DownloadDir(remoteDir, localDir string, opts TransferOptions) (string, error) {
+	var downloaded int
+	walker := s.sftpClient.Walk(remoteDir)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return "", fmt.Errorf("递归下载失败: %v", err)
+		}
+		if walker.Stat().IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(remoteDir, walker.Path())
+		if err != nil {
+			return "", fmt.Errorf("计算相对路径失败: %v", err)
+		}
+		localPath := filepath.Join(localDir, filepath.FromSlash(rel))
+		if _, err := s.downloadFileResume(walker.Path(), localPath, opts); err != nil {
+			return "", fmt.Errorf("%s: %v", rel, err)
+		}
+		downloaded++
+	}
+	return fmt.Sprintf("✅ 递归下载完成 (%d 个文件): %s -> %s", downloaded, remoteDir, localDir), nil
+}
+
+// dispatchTransfer 是 SSHExecutor.Run 里 "upload"/"download" 分支的实现：
+// 解析 -r/--resume/--limit 参数，支持本地 glob，再分流到单文件/目录两条路径
+func (s *SSHExecutor) dispatchTransfer(direction, rest string) (string, error) {
+	args, err := parseTransferArgs(strings.Fields(rest))
+	if err != nil {
+		return "", err
+	}
+
+	opts := TransferOptions{Resume: args.Resume, LimitBytesPerSec: args.LimitBPS}
+	if args.Recursive {
+		opts.Reporter = NewConsoleProgressReporter()
+	}
+
+	switch direction {
+	case "upload":
+		if args.Recursive {
+			return s.UploadDir(args.Src, args.Dst, opts)
+		}
+		matches, err := filepath.Glob(args.Src)
+		if err != nil || len(matches) == 0 {
+			return s.uploadFileResume(args.Src, args.Dst, opts)
+		}
+		if len(matches) == 1 {
+			return s.uploadFileResume(matches[0], args.Dst, opts)
+		}
+		var results []string
+		for _, m := range matches {
+			dst := path.Join(args.Dst, filepath.Base(m))
+			res, err := s.uploadFileResume(m, dst, opts)
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", m, err)
+			}
+			results = append(results, res)
+		}
+		return strings.Join(results, "\n"), nil
+
+	case "download":
+		if args.Recursive {
+			return s.DownloadDir(args.Src, args.Dst, opts)
+		}
+		matches, err := s.sftpClient.Glob(args.Src)
+		if err != nil || len(matches) == 0 {
+			return s.downloadFileResume(args.Src, args.Dst, opts)
+		}
+		if len(matches) == 1 {
+			return s.downloadFileResume(matches[0], args.Dst, opts)
+		}
+		var results []string
+		for _, m := range matches {
+			dst := filepath.Join(args.Dst, path.Base(m))
+			res, err := s.downloadFileResume(m, dst, opts)
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", m, err)
+			}
+			results = append(results, res)
+		}
+		return strings.Join(results, "\n"), nil
+
+	default:
+		return "", fmt.Errorf("未知传输方向: %s", direction)
+	}
+}
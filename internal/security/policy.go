@@ -0,0 +1,330 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// PolicyEngine 是风险判定规则的运行时表示，取代此前硬编码在 checkSingleCommand 里的
+// lowRiskVerbs/highRiskVerbs 两张表。规则来自内置默认策略 + policy.yaml 站点覆盖的合并结果
+type PolicyEngine struct {
+	mu sync.RWMutex
+
+	allowVerbs              map[string]bool
+	denyVerbs               map[string]bool
+	denyPatterns            []*regexp.Regexp
+	requireApprovalPatterns []*regexp.Regexp
+	perPlatform             map[string]*platformRules
+}
+
+// platformRules 是 per_platform 覆盖项的编译后形态 (linux/windows/powershell)
+type platformRules struct {
+	allowVerbs   map[string]bool
+	denyVerbs    map[string]bool
+	denyPatterns []*regexp.Regexp
+}
+
+// policyFile 对应 policy.yaml 的原始结构，通过 viper + mapstructure 解析
+type policyFile struct {
+	AllowVerbs              []string                    `mapstructure:"allow_verbs"`
+	DenyVerbs               []string                    `mapstructure:"deny_verbs"`
+	DenyPatterns            []string                    `mapstructure:"deny_patterns"`
+	RequireApprovalPatterns []string                    `mapstructure:"require_approval_patterns"`
+	PerPlatform             map[string]platformOverride `mapstructure:"per_platform"`
+}
+
+// platformOverride 是 per_platform 下单个平台 (linux/windows/powershell) 的原始配置
+type platformOverride struct {
+	AllowVerbs   []string `mapstructure:"allow_verbs"`
+	DenyVerbs    []string `mapstructure:"deny_verbs"`
+	DenyPatterns []string `mapstructure:"deny_patterns"`
+}
+
+// activePolicy 是当前生效的策略引擎，CheckRisk 通过它完成判定
+// 单测可以用 SetPolicyForTest 注入自定义策略，无需触碰文件系统
+var (
+	activePolicy   *PolicyEngine
+	activePolicyMu sync.RWMutex
+)
+
+func init() {
+	activePolicy = defaultPolicy()
+}
+
+// defaultPolicy 构造内置的默认策略：把原先硬编码的两张表搬进 PolicyEngine，作为 base 层
+func defaultPolicy() *PolicyEngine {
+	pf := policyFile{
+		AllowVerbs: []string{
+			"ls", "dir", "pwd", "cd",
+			"cat", "echo", "head", "tail",
+			"more", "less", "tree",
+			"find", "grep", "findstr",
+			"stat", "file", "where", "which",
+
+			"whoami", "id", "hostname", "uname",
+			"uptime", "date", "w",
+			"ps", "top", "tasklist", "free", "df", "du",
+			"ipconfig", "ifconfig", "ip", "netstat", "ss",
+			"ping", "arp", "route", "nslookup", "dig",
+			"wmic", "ver",
+
+			"mkdir", "touch", "type",
+
+			"get-childitem", "gci",
+			"get-content", "gc",
+			"get-location", "gl",
+			"get-process", "gps",
+			"get-service", "gsv",
+			"get-date", "get-host",
+			"write-host", "write-output",
+			"select-object", "where-object", "foreach-object",
+		},
+		DenyVerbs: []string{
+			"rm", "del", "erase", "rmdir",
+			"mv", "move", "cp", "copy",
+			"mkfs", "format", "fdisk", "dd",
+			"shred", "wipe",
+
+			"reboot", "shutdown", "halt", "poweroff", "init",
+			"systemctl", "service", "sc", "reg",
+			"chmod", "chown", "chgrp", "attrib",
+			"useradd", "usermod", "userdel", "passwd",
+			"sudo", "su",
+
+			"kill", "pkill", "killall", "taskkill",
+			"wget", "curl", "nc", "ncat",
+
+			"invoke-expression", "iex",
+			"start-process",
+		},
+		DenyPatterns: []string{`>`}, // 文件重定向，防止覆盖风险
+	}
+
+	engine, err := compilePolicy(pf)
+	if err != nil {
+		// 内置策略是字面量，理论上不可能编译失败；退化为空规则而不是 panic
+		return &PolicyEngine{allowVerbs: map[string]bool{}, denyVerbs: map[string]bool{}}
+	}
+	return engine
+}
+
+// compilePolicy 把原始 policyFile 编译成可直接判定的 PolicyEngine
+func compilePolicy(pf policyFile) (*PolicyEngine, error) {
+	e := &PolicyEngine{
+		allowVerbs:  toVerbSet(pf.AllowVerbs),
+		denyVerbs:   toVerbSet(pf.DenyVerbs),
+		perPlatform: make(map[string]*platformRules),
+	}
+
+	patterns, err := compilePatterns(pf.DenyPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("deny_patterns 编译失败: %v", err)
+	}
+	e.denyPatterns = patterns
+
+	approvalPatterns, err := compilePatterns(pf.RequireApprovalPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("require_approval_patterns 编译失败: %v", err)
+	}
+	e.requireApprovalPatterns = approvalPatterns
+
+	for platform, override := range pf.PerPlatform {
+		denyPatterns, err := compilePatterns(override.DenyPatterns)
+		if err != nil {
+			return nil, fmt.Errorf("per_platform.%s.deny_patterns 编译失败: %v", platform, err)
+		}
+		e.perPlatform[strings.ToLower(platform)] = &platformRules{
+			allowVerbs:   toVerbSet(override.AllowVerbs),
+			denyVerbs:    toVerbSet(override.DenyVerbs),
+			denyPatterns: denyPatterns,
+		}
+	}
+
+	return e, nil
+}
+
+func toVerbSet(verbs []string) map[string]bool {
+	set := make(map[string]bool, len(verbs))
+	for _, v := range verbs {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("正则 %q 无效: %v", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// mergePolicy 实现 base + site 覆盖的继承语义：deny 优先于 allow，site 的 deny 追加到 base 之上
+func mergePolicy(base, site *PolicyEngine) *PolicyEngine {
+	merged := &PolicyEngine{
+		allowVerbs:  mergeSets(base.allowVerbs, site.allowVerbs),
+		denyVerbs:   mergeSets(base.denyVerbs, site.denyVerbs),
+		perPlatform: make(map[string]*platformRules),
+	}
+	merged.denyPatterns = append(append([]*regexp.Regexp{}, base.denyPatterns...), site.denyPatterns...)
+	merged.requireApprovalPatterns = append(append([]*regexp.Regexp{}, base.requireApprovalPatterns...), site.requireApprovalPatterns...)
+
+	for platform, rules := range base.perPlatform {
+		merged.perPlatform[platform] = rules
+	}
+	for platform, rules := range site.perPlatform {
+		if baseRules, ok := merged.perPlatform[platform]; ok {
+			merged.perPlatform[platform] = &platformRules{
+				allowVerbs:   mergeSets(baseRules.allowVerbs, rules.allowVerbs),
+				denyVerbs:    mergeSets(baseRules.denyVerbs, rules.denyVerbs),
+				denyPatterns: append(append([]*regexp.Regexp{}, baseRules.denyPatterns...), rules.denyPatterns...),
+			}
+		} else {
+			merged.perPlatform[platform] = rules
+		}
+	}
+	return merged
+}
+
+func mergeSets(a, b map[string]bool) map[string]bool {
+	merged := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		merged[k] = true
+	}
+	for k := range b {
+		merged[k] = true
+	}
+	return merged
+}
+
+// LoadPolicy 在 config.yaml 同样的 Viper 搜索路径链上查找 policy.yaml，
+// 与内置默认策略合并后作为当前生效策略。找不到站点策略文件时静默回退到内置默认值
+func LoadPolicy() error {
+	v := viper.New()
+	v.SetConfigName("policy")
+	v.SetConfigType("yaml")
+	v.AddConfigPath(".")
+	if home, err := os.UserHomeDir(); err == nil {
+		v.AddConfigPath(filepath.Join(home, ".deepsentry"))
+	}
+	v.AddConfigPath("/etc/deepsentry")
+
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			setActivePolicy(defaultPolicy())
+			return nil
+		}
+		return fmt.Errorf("policy.yaml 读取错误: %w", err)
+	}
+
+	var pf policyFile
+	if err := v.Unmarshal(&pf); err != nil {
+		return fmt.Errorf("policy.yaml 解析失败: %w", err)
+	}
+
+	site, err := compilePolicy(pf)
+	if err != nil {
+		return err
+	}
+
+	setActivePolicy(mergePolicy(defaultPolicy(), site))
+	return nil
+}
+
+func setActivePolicy(p *PolicyEngine) {
+	activePolicyMu.Lock()
+	defer activePolicyMu.Unlock()
+	activePolicy = p
+}
+
+func getActivePolicy() *PolicyEngine {
+	activePolicyMu.RLock()
+	defer activePolicyMu.RUnlock()
+	return activePolicy
+}
+
+// SetPolicyForTest 允许单元测试直接注入一份策略，绕过文件系统
+func SetPolicyForTest(pf policyFile) error {
+	engine, err := compilePolicy(pf)
+	if err != nil {
+		return err
+	}
+	setActivePolicy(engine)
+	return nil
+}
+
+// evaluatePatterns 只检查 deny_patterns（含 per_platform 覆盖），供 CheckRisk 在拆分子命令前
+// 对归一化后的完整命令做一次全局扫描，对应此前硬编码的 `>` 重定向检测
+func (e *PolicyEngine) evaluatePatterns(fullCmd, platform string) (risk string, reason string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if rules, ok := e.perPlatform[strings.ToLower(platform)]; ok {
+		for _, re := range rules.denyPatterns {
+			if re.MatchString(fullCmd) {
+				return "high", fmt.Sprintf("命中平台规则(%s): %s", platform, re.String())
+			}
+		}
+	}
+	for _, re := range e.denyPatterns {
+		if re.MatchString(fullCmd) {
+			if re.String() == ">" {
+				return "high", "检测到文件重定向 (>)"
+			}
+			return "high", fmt.Sprintf("命中高危规则: %s", re.String())
+		}
+	}
+	return "low", ""
+}
+
+// Evaluate 是策略引擎的核心判定逻辑，取代原先的 checkSingleCommand 硬编码查表
+// platform 取 "linux"/"windows"/"powershell"，不匹配则只按通用规则判定
+func (e *PolicyEngine) Evaluate(verb, fullCmd, platform string) (risk string, reason string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if rules, ok := e.perPlatform[strings.ToLower(platform)]; ok {
+		if rules.denyVerbs[verb] {
+			return "high", fmt.Sprintf("敏感指令(%s 平台): %s", platform, verb)
+		}
+		for _, re := range rules.denyPatterns {
+			if re.MatchString(fullCmd) {
+				return "high", fmt.Sprintf("命中平台规则(%s): %s", platform, re.String())
+			}
+		}
+		if rules.allowVerbs[verb] {
+			return "low", "安全操作"
+		}
+	}
+
+	for _, re := range e.denyPatterns {
+		if re.MatchString(fullCmd) {
+			return "high", fmt.Sprintf("命中高危规则: %s", re.String())
+		}
+	}
+
+	if e.denyVerbs[verb] {
+		return "high", fmt.Sprintf("敏感指令: %s", verb)
+	}
+	if e.allowVerbs[verb] {
+		return "low", "安全操作"
+	}
+
+	for _, re := range e.requireApprovalPatterns {
+		if re.MatchString(fullCmd) {
+			return "high", fmt.Sprintf("命中需人工确认规则: %s", re.String())
+		}
+	}
+
+	return "high", fmt.Sprintf("未知指令(%s)，需人工确认", verb)
+}
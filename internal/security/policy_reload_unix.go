@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package security
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchPolicyReload 监听 SIGHUP，收到信号后重新加载 policy.yaml
+// Windows 下没有 SIGHUP 语义，由 policy_reload_windows.go 提供空实现
+func WatchPolicyReload() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			if err := LoadPolicy(); err != nil {
+				fmt.Printf("⚠️ [策略重载] policy.yaml 重新加载失败: %v\n", err)
+			} else {
+				fmt.Println("🔄 [策略重载] 已收到 SIGHUP，policy.yaml 重新加载完成")
+			}
+		}
+	}()
+}
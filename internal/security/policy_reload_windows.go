@@ -0,0 +1,7 @@
+//go:build windows
+// +build windows
+
+package security
+
+// WatchPolicyReload 在 Windows 下没有 SIGHUP 等价信号，保留空实现以维持跨平台调用一致
+func WatchPolicyReload() {}
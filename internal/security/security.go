@@ -1,9 +1,16 @@
 package security
 
 import (
+	"ai-edr/internal/audit"
+	"ai-edr/internal/config"
 	"ai-edr/internal/executor"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"os/user"
+	"runtime"
 	"strings"
 	"sync"
 )
@@ -62,9 +69,10 @@ func CheckRisk(cmd string) (string, string) {
 	analyzeCmd = cleanShellWrapper(analyzeCmd)
 
 	// 2. 全局高危特征检测
-	// 检测重定向 (>)，防止文件覆盖风险
-	if strings.Contains(analyzeCmd, ">") {
-		return "high", "检测到文件重定向 (>)"
+	// deny_patterns (含默认的重定向 `>` 规则) 针对归一化后的完整命令生效
+	policy := getActivePolicy()
+	if risk, reason := policy.evaluatePatterns(analyzeCmd, currentPlatform()); risk == "high" {
+		return risk, reason
 	}
 
 	// 3. 复合命令拆分逻辑
@@ -132,78 +140,76 @@ func checkSingleCommand(subCmd string) (string, string) {
 	// 二次清洗：防止动词本身带引号 (如 "cd")
 	verb = strings.Trim(verb, "\"'")
 
-	// --- 白名单 (Low Risk) ---
-	lowRiskVerbs := map[string]bool{
-		// 浏览与查看
-		"ls": true, "dir": true, "pwd": true, "cd": true,
-		"cat": true, "echo": true, "head": true, "tail": true,
-		"more": true, "less": true, "tree": true,
-		"find": true, "grep": true, "findstr": true,
-		"stat": true, "file": true, "where": true, "which": true,
-
-		// 系统/网络信息
-		"whoami": true, "id": true, "hostname": true, "uname": true,
-		"uptime": true, "date": true, "w": true,
-		"ps": true, "top": true, "tasklist": true, "free": true, "df": true, "du": true,
-		"ipconfig": true, "ifconfig": true, "ip": true, "netstat": true, "ss": true,
-		"ping": true, "arp": true, "route": true, "nslookup": true, "dig": true,
-		"wmic": true, "ver": true,
-
-		// 文件操作 (非破坏性)
-		"mkdir": true, "touch": true, "type": true,
-
-		// 🟢 [新增] PowerShell 常用安全动词
-		// 注意：已移除重复的 "ls"
-		"get-childitem": true, "gci": true,
-		"get-content": true, "gc": true,
-		"get-location": true, "gl": true,
-		"get-process": true, "gps": true,
-		"get-service": true, "gsv": true,
-		"get-date": true, "get-host": true,
-		"write-host": true, "write-output": true,
-		"select-object": true, "where-object": true, "foreach-object": true,
-	}
-
-	if lowRiskVerbs[verb] {
-		return "low", "安全操作"
-	}
-
-	// --- 黑名单 (High Risk) ---
-	highRiskVerbs := map[string]bool{
-		// 破坏性操作
-		"rm": true, "del": true, "erase": true, "rmdir": true,
-		"mv": true, "move": true, "cp": true, "copy": true,
-		"mkfs": true, "format": true, "fdisk": true, "dd": true,
-		"shred": true, "wipe": true,
-
-		// 系统控制与权限
-		"reboot": true, "shutdown": true, "halt": true, "poweroff": true, "init": true,
-		"systemctl": true, "service": true, "sc": true, "reg": true,
-		"chmod": true, "chown": true, "chgrp": true, "attrib": true,
-		"useradd": true, "usermod": true, "userdel": true, "passwd": true,
-		"sudo": true, "su": true,
-
-		// 进程与网络传输
-		"kill": true, "pkill": true, "killall": true, "taskkill": true,
-		"wget": true, "curl": true, "nc": true, "ncat": true,
-
-		// PowerShell 敏感操作
-		"invoke-expression": true, "iex": true,
-		"start-process": true,
-	}
-
-	if highRiskVerbs[verb] {
-		return "high", fmt.Sprintf("敏感指令: %s", verb)
-	}
-
-	// --- 默认策略 ---
-	return "high", fmt.Sprintf("未知指令(%s)，需人工确认", verb)
+	// 动词级别的允许/拒绝判定，连同 per_platform 覆盖，交给策略引擎统一决策
+	// (原先硬编码的 lowRiskVerbs/highRiskVerbs 两张表已迁移为 policy.yaml 的内置默认策略)
+	return getActivePolicy().Evaluate(verb, subCmd, currentPlatform())
+}
+
+// currentPlatform 把 runtime.GOOS 映射为 policy.yaml 里 per_platform 使用的键名
+func currentPlatform() string {
+	if runtime.GOOS == "windows" {
+		return "windows"
+	}
+	return "linux"
 }
 
 // SafeExecV3 执行命令的安全封装
-func SafeExecV3(cmd string) (string, error) {
+// 它也是哈希链审计日志的落点：经由它执行的命令，无论走 LocalExecutor、SSHExecutor
+// 还是 FanoutExecutor，都会在这里留下一条记录。baseline 包的检查命令直接调用
+// Executor.Run，不经过这里，既不过 CheckRisk，也不写审计日志。
+// approvedBy 是这条命令的批准者身份，高危命令经由 daemon 的审批流程放行时由调用方传入；
+// 低危命令无需人工批准，传空字符串即可
+func SafeExecV3(cmd string, approvedBy string) (string, error) {
 	if executor.Current == nil {
 		return "", fmt.Errorf("执行器未初始化")
 	}
-	return executor.Current.Run(cmd)
+
+	risk, reason := CheckRisk(cmd)
+	out, err := executor.Current.Run(cmd)
+
+	rec := audit.Record{
+		Host:       currentHost(),
+		User:       currentUser(),
+		Cmd:        cmd,
+		Risk:       risk,
+		Reason:     reason,
+		ApprovedBy: approvedBy,
+		ExitCode:   exitCodeFor(err),
+	}
+	if cwd, cwdErr := os.Getwd(); cwdErr == nil {
+		rec.Cwd = cwd
+	}
+	sum := sha256.Sum256([]byte(out))
+	rec.StdoutSHA256 = hex.EncodeToString(sum[:])
+
+	audit.Log(rec)
+
+	return out, err
+}
+
+// currentHost 返回用于审计记录的主机标识：远程模式下是配置的 SSH 目标，否则是本机 hostname
+func currentHost() string {
+	if config.GlobalConfig.SSHHost != "" {
+		return config.GlobalConfig.SSHHost
+	}
+	if h, err := os.Hostname(); err == nil {
+		return h
+	}
+	return "unknown"
+}
+
+// currentUser 返回用于审计记录的操作者标识
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// exitCodeFor 把 Run 返回的 error 折算成一个粗粒度的退出码，0 表示成功
+func exitCodeFor(err error) int {
+	if err == nil {
+		return 0
+	}
+	return 1
 }